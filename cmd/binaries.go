@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// Environment variables that set the external binaries when the matching
+// --*-bin flag isn't given
+const (
+	envPingBin   = "NETCHECK_PING_BIN"
+	envPythonBin = "NETCHECK_PYTHON_BIN"
+	envPwshBin   = "NETCHECK_PWSH_BIN"
+	envSSHBin    = "NETCHECK_SSH_BIN"
+)
+
+// binarySetting resolves a --*-bin flag, falling back to its environment
+// variable
+func binarySetting(flag, env string) string {
+	if flag != "" {
+		return flag
+	}
+	return os.Getenv(env)
+}
+
+// validateBinaries checks that every overridden binary exists and is
+// executable, but only for check types the config actually uses, so a
+// stale setting doesn't break configs that never call it
+func validateBinaries(hosts []core.Host, opts *core.Options) error {
+	binaries := map[string]struct{ name, path string }{
+		"ICMP": {"ping", opts.PingBin},
+		"PY":   {"python", opts.PythonBin},
+		"PS":   {"pwsh", opts.PwshBin},
+	}
+	checked := map[string]bool{}
+	for _, host := range hosts {
+		bin, ok := binaries[host.CheckType]
+		if !ok || bin.path == "" || checked[host.CheckType] {
+			continue
+		}
+		checked[host.CheckType] = true
+		if _, err := exec.LookPath(bin.path); err != nil {
+			return fmt.Errorf("%s binary: %w", bin.name, err)
+		}
+	}
+	// via= checks of any type run through the ssh client
+	if opts.SSHBin != "" && slices.ContainsFunc(hosts, func(h core.Host) bool { return h.Tokens.Has("via") }) {
+		if _, err := exec.LookPath(opts.SSHBin); err != nil {
+			return fmt.Errorf("ssh binary: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,764 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// Supported config formats
+const (
+	formatText = "text"
+	formatYAML = "yaml"
+	formatJSON = "json"
+)
+
+// Precompiled regex for config lines: 2-4 char check type + separator +
+// hostname. configureSyntax rebuilds it for --field-sep.
+var reLine = regexp.MustCompile(`^([a-zA-Z0-9]{2,4})\s+(.+)$`)
+
+// Text config syntax, set by --comment-char and --field-sep. A zero
+// fieldSep splits on runs of whitespace.
+var (
+	commentChar = "#"
+	fieldSep    rune
+)
+
+// configureSyntax validates and applies --comment-char and --field-sep.
+// The separator may be given as "tab" or "\t".
+func configureSyntax(comment, sep string) error {
+	cr := []rune(comment)
+	if len(cr) != 1 || unicode.IsSpace(cr[0]) || !syntaxCharAllowed(cr[0]) {
+		return fmt.Errorf("invalid --comment-char %q: must be one non-space, non-alphanumeric character other than = ! @ or a quote", comment)
+	}
+
+	var sr rune
+	switch sep {
+	case "":
+	case "tab", `\t`:
+		sr = '\t'
+	default:
+		runes := []rune(sep)
+		if len(runes) != 1 || (!unicode.IsSpace(runes[0]) && !syntaxCharAllowed(runes[0])) {
+			return fmt.Errorf("invalid --field-sep %q: must be one non-alphanumeric character other than = ! @ or a quote", sep)
+		}
+		sr = runes[0]
+	}
+	if sr == cr[0] {
+		return fmt.Errorf("--comment-char and --field-sep can't both be %q", comment)
+	}
+
+	commentChar, fieldSep = comment, sr
+	if sr == 0 {
+		reLine = regexp.MustCompile(`^([a-zA-Z0-9]{2,4})\s+(.+)$`)
+	} else {
+		reLine = regexp.MustCompile(`^([a-zA-Z0-9]{2,4})[ \t]*` + regexp.QuoteMeta(string(sr)) + `[ \t]*(.+)$`)
+	}
+	return nil
+}
+
+// syntaxCharAllowed reports whether r can serve as a comment character or
+// field separator without clashing with the rest of the line syntax
+func syntaxCharAllowed(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return false
+	}
+	return !strings.ContainsRune(`=!@"'<`, r)
+}
+
+// Precompiled regex for per-host tokens: key=value (e.g. clientcert=client.pem)
+var reToken = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_-]*)=(.*)$`)
+
+// Precompiled regex for limit tokens: key<value (e.g. ttfb<500ms), shorthand
+// for key=value that reads as the assertion it is
+var reLimitToken = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_-]*)<(.+)$`)
+
+// Precompiled regex for match tokens: key~=pattern (e.g. version~=1\.4\.2),
+// shorthand for key=pattern that reads as the match it is
+var reMatchToken = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_-]*)~=(.+)$`)
+
+// Precompiled regex for check type codes in structured (YAML/JSON) configs
+var reCheckType = regexp.MustCompile(`^[a-zA-Z0-9]{2,4}$`)
+
+// structuredConfig is the YAML/JSON config layout:
+//
+//	hosts:
+//	  - type: htps
+//	    host: api.internal
+//	    options:
+//	      cacert: ca.pem
+//
+// An optional defaults map sets options for every host of a check type:
+//
+//	defaults:
+//	  http:
+//	    timeout: 10s
+type structuredConfig struct {
+	Defaults map[string]map[string]stringList `json:"defaults" yaml:"defaults"`
+	Hosts    []structuredHost                 `json:"hosts" yaml:"hosts"`
+}
+
+type structuredHost struct {
+	Type    string                `json:"type" yaml:"type"`
+	Host    string                `json:"host" yaml:"host"`
+	Name    string                `json:"name" yaml:"name"`
+	Options map[string]stringList `json:"options" yaml:"options"`
+	// Enabled: false (or disabled: true) keeps the entry without checking it
+	Enabled  *bool `json:"enabled" yaml:"enabled"`
+	Disabled bool  `json:"disabled" yaml:"disabled"`
+}
+
+// stringList accepts either a single string or a list of strings so that
+// repeatable options can be written naturally in YAML/JSON
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("option value must be a string or list of strings")
+	}
+	*l = list
+	return nil
+}
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return fmt.Errorf("option value must be a string or list of strings")
+	}
+	*l = list
+	return nil
+}
+
+// detectConfigFormat picks the parser from the file extension, defaulting to
+// the text format (which is also assumed for stdin)
+func detectConfigFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".json":
+		return formatJSON
+	default:
+		return formatText
+	}
+}
+
+// contentReader notes whether anything other than whitespace was read, so
+// an empty config can be told apart from one whose lines are all comments
+type contentReader struct {
+	r          io.Reader
+	hasContent bool
+}
+
+func (c *contentReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if !c.hasContent && len(bytes.TrimSpace(p[:n])) > 0 {
+		c.hasContent = true
+	}
+	return n, err
+}
+
+// configFetchTimeout bounds downloading a config from a URL
+const configFetchTimeout = 30 * time.Second
+
+// isConfigURL reports whether a --config value is an HTTP(S) URL
+func isConfigURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// fetchConfig downloads a config served over HTTP(S) with the run's CA and
+// proxy settings. The body is read once and kept for the rest of the run.
+// When the URL path has no known extension, a YAML or JSON Content-Type
+// picks the format.
+func fetchConfig(rawURL string, opts *core.Options) ([]byte, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid config URL: %w", err)
+	}
+	client := core.NewHTTPClient(opts, configFetchTimeout)
+	defer client.CloseIdleConnections()
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch config: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", fmt.Errorf("fetch config: server returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch config: %w", err)
+	}
+
+	format := detectConfigFormat(u.Path)
+	if format == formatText {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		switch {
+		case strings.Contains(mediaType, "yaml"):
+			format = formatYAML
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			format = formatJSON
+		}
+	}
+	return data, format, nil
+}
+
+// hostsFromConfig loads hosts from path ("-" for stdin, or an http(s) URL)
+// using the given format, or the format detected from the file extension
+// when empty. It also reports whether the source had any non-blank content.
+func hostsFromConfig(path, format string, opts *core.Options) ([]core.Host, bool, error) {
+	var src io.Reader
+	if path == "-" {
+		src = os.Stdin
+	} else if isConfigURL(path) {
+		data, detected, err := fetchConfig(path, opts)
+		if err != nil {
+			return nil, false, err
+		}
+		if format == "" {
+			format = detected
+		}
+		src = bytes.NewReader(data)
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, false, err
+		}
+		defer file.Close()
+		src = file
+	}
+	r := &contentReader{r: src}
+	hosts, err := hostsFromReader(r, path, format)
+	if err != nil {
+		return nil, r.hasContent, err
+	}
+	hosts, err = orderHosts(hosts)
+	return hosts, r.hasContent, err
+}
+
+// configDirExts are the file extensions --config-dir loads
+var configDirExts = map[string]bool{".txt": true, ".yaml": true, ".yml": true, ".json": true}
+
+// hostsFromDir loads every config file directly inside dir, in filename
+// order, as one combined config; depends= may name hosts from other files.
+// Each file's format is detected from its extension unless format is set.
+func hostsFromDir(dir, format string) ([]core.Host, bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var hosts []core.Host
+	hasContent, files := false, 0
+	for _, entry := range entries {
+		if entry.IsDir() || !configDirExts[strings.ToLower(filepath.Ext(entry.Name()))] {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, false, err
+		}
+		r := &contentReader{r: file}
+		fileHosts, err := hostsFromReader(r, path, format)
+		file.Close()
+		if err != nil {
+			return nil, false, err
+		}
+		log.Info().Str("file", path).Int("hostCount", len(fileHosts)).Msg("config file loaded")
+		hosts = append(hosts, fileHosts...)
+		hasContent = hasContent || r.hasContent
+		files++
+	}
+	if files == 0 {
+		return nil, false, fmt.Errorf("no config files (*.txt, *.yaml, *.yml, *.json) in %s", dir)
+	}
+
+	hosts, err = orderHosts(hosts)
+	return hosts, hasContent, err
+}
+
+// hostsFromReader parses a config stream in the given (or detected) format
+func hostsFromReader(r io.Reader, path, format string) ([]core.Host, error) {
+	if format == "" {
+		format = detectConfigFormat(path)
+	}
+
+	var hosts []core.Host
+	var err error
+	switch format {
+	case formatText:
+		hosts, err = hostsFromText(r, path, 0)
+	case formatYAML, formatJSON:
+		hosts, err = hostsFromStructured(r, path, format)
+	default:
+		return nil, fmt.Errorf("unknown config format %q (valid: text, yaml, json)", format)
+	}
+	return hosts, err
+}
+
+// orderHosts puts loaded hosts in run order: higher priorities first, then
+// prerequisites pulled ahead of their dependents. Cycles are config errors.
+func orderHosts(hosts []core.Host) ([]core.Host, error) {
+	hosts, err := orderByPriority(hosts)
+	if err != nil {
+		return nil, err
+	}
+	return orderByDependencies(hosts)
+}
+
+// defaultsDirective starts a text config line of per-check-type defaults
+const defaultsDirective = "@defaults"
+
+// checkDefaults maps canonical check types to the tokens every host of that
+// type inherits unless it sets the same key itself
+type checkDefaults map[string]core.Tokens
+
+// apply merges the defaults into each host's tokens
+func (d checkDefaults) apply(hosts []core.Host) {
+	for i := range hosts {
+		defaults := d[hosts[i].CheckType]
+		if len(defaults) == 0 {
+			continue
+		}
+		if hosts[i].Tokens == nil {
+			hosts[i].Tokens = core.Tokens{}
+		}
+		for key, values := range defaults {
+			if hosts[i].Tokens.Has(key) {
+				continue
+			}
+			for _, value := range values {
+				hosts[i].Tokens.Add(key, value)
+			}
+		}
+	}
+}
+
+// add records default tokens for a check type code (aliases allowed)
+func (d checkDefaults) add(checkType string, tokens core.Tokens) error {
+	if !reCheckType.MatchString(checkType) {
+		return fmt.Errorf("invalid check type %q in defaults (must be 2-4 characters)", checkType)
+	}
+	if tokens.Has("name") {
+		return fmt.Errorf("name can't be set in defaults")
+	}
+	code := core.CanonicalCheckType(checkType)
+	if d[code] == nil {
+		d[code] = core.Tokens{}
+	}
+	for key, values := range tokens {
+		d[code][key] = values
+	}
+	return nil
+}
+
+// checkTokens reports tokens a check type doesn't read, usually typos: an
+// error under --strict-tokens, otherwise a warning naming where they are
+func checkTokens(where, checkType string, tokens core.Tokens) error {
+	unknown := core.UnknownTokens(checkType, tokens)
+	if len(unknown) == 0 {
+		return nil
+	}
+	if strictTokens {
+		return fmt.Errorf("unknown token %s for %s checks", strings.Join(unknown, ", "), checkType)
+	}
+	log.Warn().Str("at", where).Str("checkType", checkType).Strs("tokens", unknown).Msg("ignoring unknown tokens (--strict-tokens makes this an error)")
+	return nil
+}
+
+// parseDefaultsLine parses "@defaults TYPE key=value ..."
+func parseDefaultsLine(line string) (string, core.Tokens, error) {
+	fields, err := splitConfigFields(strings.TrimPrefix(line, defaultsDirective))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(fields) < 2 {
+		return "", nil, fmt.Errorf("invalid format: want '%s TYPE key=value ...'", defaultsDirective)
+	}
+	tokens := core.Tokens{}
+	for _, field := range fields[1:] {
+		tm := reToken.FindStringSubmatch(field)
+		if tm == nil {
+			return "", nil, fmt.Errorf("invalid format: %q is not a key=value token", field)
+		}
+		tokens.Add(strings.ToLower(tm[1]), tm[2])
+	}
+	return fields[0], tokens, nil
+}
+
+// Stream directly from config file to hosts to avoid keeping all lines in
+// memory. depth counts the @exec commands whose output is being parsed.
+func hostsFromText(r io.Reader, path string, depth int) ([]core.Host, error) {
+	hosts := make([]core.Host, 0, 128)
+	defaults := checkDefaults{}
+	// Raw lines kept for writing hosts back out (--failed-config): the
+	// comment block above the current line and the @defaults per check type
+	var comments []string
+	defaultLines := map[string][]string{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			comments = nil
+			continue
+		}
+		if strings.HasPrefix(line, commentChar) {
+			comments = append(comments, line)
+			continue
+		}
+
+		var err error
+		if rest, ok := strings.CutPrefix(line, defaultsDirective); ok && rest != "" && isFieldSep([]rune(rest)[0]) {
+			var checkType string
+			var tokens core.Tokens
+			checkType, tokens, err = parseDefaultsLine(line)
+			if err == nil {
+				err = checkTokens(fmt.Sprintf("%s line %d", path, lineNum), core.CanonicalCheckType(checkType), tokens)
+			}
+			if err == nil {
+				err = defaults.add(checkType, tokens)
+			}
+			if err == nil {
+				code := core.CanonicalCheckType(checkType)
+				defaultLines[code] = append(defaultLines[code], line)
+			}
+		} else if rest, ok := strings.CutPrefix(line, execDirective); ok && (rest == "" || isFieldSep([]rune(rest)[0])) {
+			// A config fetched over HTTP mustn't run commands here
+			if isConfigURL(path) {
+				err = fmt.Errorf("%s isn't allowed in remote configs", execDirective)
+			} else {
+				// Errors come back naming this line, and the output line
+				// for parse errors
+				expanded, err := hostsFromExec(line, fmt.Sprintf("%s line %d", path, lineNum), depth)
+				if err != nil {
+					return nil, err
+				}
+				hosts = append(hosts, expanded...)
+			}
+		} else {
+			var expanded []core.Host
+			expanded, err = parseHostLine(line)
+			source := &core.HostSource{Line: line, Comments: comments}
+			for i, h := range expanded {
+				if err == nil {
+					err = checkTokens(fmt.Sprintf("%s line %d", path, lineNum), h.CheckType, h.Tokens)
+				}
+				expanded[i].Source = source
+			}
+			if err == nil {
+				expanded, err = expandHostRanges(expanded)
+			}
+			hosts = append(hosts, expanded...)
+		}
+		comments = nil
+		if err != nil {
+			if path == "-" {
+				return nil, fmt.Errorf("stdin line %d: %w (stdin is read as text; use --config-format for yaml or json)", lineNum, err)
+			}
+			return nil, fmt.Errorf("%s line %d: %w", path, lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan %s: %w", path, err)
+	}
+
+	// Defaults apply wherever they appear in the file
+	defaults.apply(hosts)
+	for _, h := range hosts {
+		for _, line := range defaultLines[h.CheckType] {
+			if !slices.Contains(h.Source.Defaults, line) {
+				h.Source.Defaults = append(h.Source.Defaults, line)
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// hostsFromStructured decodes a YAML or JSON config document
+func hostsFromStructured(r io.Reader, path, format string) ([]core.Host, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var cfg structuredConfig
+	if format == formatJSON {
+		err = json.Unmarshal(data, &cfg)
+	} else {
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s as %s: %w", path, format, err)
+	}
+
+	defaults := checkDefaults{}
+	for checkType, options := range cfg.Defaults {
+		tokens := core.Tokens{}
+		for key, values := range options {
+			for _, value := range values {
+				tokens.Add(strings.ToLower(key), value)
+			}
+		}
+		err := checkTokens(fmt.Sprintf("%s defaults %s", path, checkType), core.CanonicalCheckType(checkType), tokens)
+		if err == nil {
+			err = defaults.add(checkType, tokens)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse %s as %s: %w", path, format, err)
+		}
+	}
+
+	hosts := make([]core.Host, 0, len(cfg.Hosts))
+	for i, entry := range cfg.Hosts {
+		if !reCheckType.MatchString(entry.Type) {
+			return nil, fmt.Errorf("parse %s as %s: host %d: invalid check type %q (must be 2-4 characters)", path, format, i+1, entry.Type)
+		}
+		if strings.TrimSpace(entry.Host) == "" {
+			return nil, fmt.Errorf("parse %s as %s: host %d: missing host", path, format, i+1)
+		}
+
+		tokens := core.Tokens{}
+		for key, values := range entry.Options {
+			for _, value := range values {
+				tokens.Add(strings.ToLower(key), value)
+			}
+		}
+		checkType := core.CanonicalCheckType(entry.Type)
+		if err := checkTokens(fmt.Sprintf("%s host %d", path, i+1), checkType, tokens); err != nil {
+			return nil, fmt.Errorf("parse %s as %s: host %d: %w", path, format, i+1, err)
+		}
+		hosts = append(hosts, core.Host{
+			CheckType: checkType,
+			HostName:  strings.TrimSpace(entry.Host),
+			Label:     strings.TrimSpace(entry.Name),
+			Tokens:    tokens,
+			Disabled:  entry.Disabled || (entry.Enabled != nil && !*entry.Enabled),
+		})
+	}
+	hosts, err = expandHostRanges(hosts)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s as %s: %w", path, format, err)
+	}
+	defaults.apply(hosts)
+	return hosts, nil
+}
+
+// Combined check types at the start of a host line: ICMP+HTTP or ICMP,HTTP
+// (only "+" when --field-sep is a comma)
+var (
+	reComboTypes      = regexp.MustCompile(`^(!?\s*)([a-zA-Z0-9]{2,4}(?:[+,][a-zA-Z0-9]{2,4})+)(.*)$`)
+	reComboTypesPlain = regexp.MustCompile(`^(!?\s*)([a-zA-Z0-9]{2,4}(?:\+[a-zA-Z0-9]{2,4})+)(.*)$`)
+)
+
+// parseHostLine parses a config host line. A combined check type expands
+// into one host per type, each sharing the hostname, tokens, and label of
+// the line; every code must be a known check type (after aliases).
+func parseHostLine(input string) ([]core.Host, error) {
+	re := reComboTypes
+	if fieldSep == ',' {
+		re = reComboTypesPlain
+	}
+	m := re.FindStringSubmatch(strings.TrimSpace(input))
+	if m == nil {
+		h, err := parseHostString(input)
+		if err != nil {
+			return nil, err
+		}
+		return []core.Host{*h}, nil
+	}
+
+	codes := strings.FieldsFunc(m[2], func(r rune) bool { return r == '+' || r == ',' })
+	seen := make(map[string]bool, len(codes))
+	hosts := make([]core.Host, 0, len(codes))
+	for _, code := range codes {
+		checkType := core.CanonicalCheckType(code)
+		if _, ok := core.CheckTypes[checkType]; !ok {
+			return nil, fmt.Errorf("unknown check type %q in %s", code, m[2])
+		}
+		if seen[checkType] {
+			return nil, fmt.Errorf("check type %s repeated in %s", checkType, m[2])
+		}
+		seen[checkType] = true
+
+		h, err := parseHostString(m[1] + code + m[3])
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, *h)
+	}
+	return hosts, nil
+}
+
+func parseHostString(input string) (*core.Host, error) {
+	input = strings.TrimSpace(input)
+
+	// A leading "!" disables the host without deleting its line
+	disabled := false
+	if rest, ok := strings.CutPrefix(input, "!"); ok {
+		disabled = true
+		input = strings.TrimSpace(rest)
+	}
+
+	// A trailing "#name:Friendly Name" comment sets the host label
+	var label string
+	if idx := strings.Index(input, commentChar+"name:"); idx > 0 {
+		label = strings.TrimSpace(input[idx+len(commentChar+"name:"):])
+		input = strings.TrimSpace(input[:idx])
+	}
+
+	matches := reLine.FindStringSubmatch(input)
+
+	if matches == nil {
+		return nil, fmt.Errorf("invalid format: must be '2-4 char checktype hostname'")
+	}
+
+	fields, err := splitConfigFields(matches[2])
+	if err != nil {
+		return nil, err
+	}
+
+	// Separate key=value tokens from the hostname (and script name) fields
+	checkType := core.CanonicalCheckType(matches[1])
+	tokens := core.Tokens{}
+	var hostFields []string
+	for _, field := range fields {
+		if tm := reToken.FindStringSubmatch(field); tm != nil {
+			key := strings.ToLower(tm[1])
+			if key == "name" {
+				label = tm[2]
+				continue
+			}
+			// A MULT member's weight= stays next to its URL in the hostname
+			if checkType == "MULT" && key == "weight" {
+				hostFields = append(hostFields, field)
+				continue
+			}
+			tokens.Add(key, tm[2])
+			continue
+		}
+		if tm := reLimitToken.FindStringSubmatch(field); tm != nil {
+			tokens.Add(strings.ToLower(tm[1]), tm[2])
+			continue
+		}
+		if tm := reMatchToken.FindStringSubmatch(field); tm != nil {
+			tokens.Add(strings.ToLower(tm[1]), tm[2])
+			continue
+		}
+		// A bare "disabled" after the hostname works like the "!" prefix
+		if len(hostFields) > 0 && strings.EqualFold(field, "disabled") {
+			disabled = true
+			continue
+		}
+		hostFields = append(hostFields, field)
+	}
+	if len(hostFields) == 0 {
+		return nil, fmt.Errorf("invalid format: missing hostname")
+	}
+
+	return &core.Host{
+		CheckType: checkType,
+		HostName:  strings.Join(hostFields, " "),
+		Label:     label,
+		Tokens:    tokens,
+		Disabled:  disabled,
+	}, nil
+}
+
+// splitFields splits a config line on whitespace, keeping quoted sections
+// together so token values may contain spaces (name="Core Gateway").
+// Single quotes opening a field or a token value work the same way and keep
+// double quotes literal, so inline JSON can be written as body='{"a":1}'.
+// Elsewhere an apostrophe is literal (name=O'Brien).
+func splitFields(input string) ([]string, error) {
+	return splitFieldsFunc(input, func(r rune) bool { return r == ' ' || r == '\t' })
+}
+
+// isFieldSep reports whether r separates text config fields
+func isFieldSep(r rune) bool {
+	if fieldSep == 0 {
+		return r == ' ' || r == '\t'
+	}
+	return r == fieldSep
+}
+
+// splitConfigFields splits a text config line on the configured field
+// separator. With --field-sep, whitespace around each field is dropped and
+// empty fields are skipped.
+func splitConfigFields(input string) ([]string, error) {
+	fields, err := splitFieldsFunc(input, isFieldSep)
+	if err != nil || fieldSep == 0 {
+		return fields, err
+	}
+	kept := fields[:0]
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			kept = append(kept, field)
+		}
+	}
+	return kept, nil
+}
+
+// splitFieldsFunc is splitFields with a caller-chosen separator
+func splitFieldsFunc(input string, isSep func(rune) bool) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	var quote, prev rune
+	inField := false
+
+	for _, r := range input {
+		opensSingle := r == '\'' && (!inField || prev == '=')
+		prev = r
+		switch {
+		case quote == 0 && (r == '"' || opensSingle):
+			quote = r
+			inField = true
+		case r == quote:
+			quote = 0
+		case quote == 0 && isSep(r):
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		case quote == 0 && !inField && (r == ' ' || r == '\t'):
+			// Padding before a field when the separator isn't whitespace
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("invalid format: unterminated quote")
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
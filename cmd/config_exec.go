@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// execDirective starts a text config line whose command prints more config
+// lines, e.g. "@exec inventory list --format netcheck"
+const execDirective = "@exec"
+
+// Limits on @exec commands: how long one may run, how much stdout it may
+// print, and how deeply @exec lines in its output may nest
+const (
+	execTimeout   = 30 * time.Second
+	execOutputCap = 1 << 20
+	execMaxDepth  = 4
+)
+
+// execStderrCap is how much of a failed command's stderr goes in the error
+const execStderrCap = 4 << 10
+
+// hostsFromExec runs an "@exec command args" line and parses its stdout as
+// a text config, with the same comment, field-separator, and directive
+// rules as a file. The command runs directly, not through a shell; quote
+// arguments as in host lines. A non-zero exit, a timeout, or more than
+// execOutputCap of output fails the load.
+func hostsFromExec(line, where string, depth int) ([]core.Host, error) {
+	if depth >= execMaxDepth {
+		return nil, fmt.Errorf("%s: %s nested more than %d deep", where, execDirective, execMaxDepth)
+	}
+	fields, err := splitConfigFields(strings.TrimPrefix(line, execDirective))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", where, err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s: invalid format: want '%s command [args...]'", where, execDirective)
+	}
+
+	out, err := runExecCommand(fields[0], fields[1:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s %s: %w", where, execDirective, fields[0], err)
+	}
+	hosts, err := hostsFromText(bytes.NewReader(out), where+" "+execDirective+" output", depth+1)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("at", where).Str("command", fields[0]).Int("hostCount", len(hosts)).Msg("config lines loaded from command")
+	return hosts, nil
+}
+
+// runExecCommand runs name with args and returns its stdout, failing on a
+// non-zero exit, execTimeout, or output past execOutputCap
+func runExecCommand(name string, args []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	// Children of a killed command can hold its pipes open; don't wait on
+	// them
+	cmd.WaitDelay = time.Second
+	// Runaway output kills the command as soon as it passes the cap
+	stdout := &cappedBuffer{limit: execOutputCap, overflow: cancel}
+	stderr := &cappedBuffer{limit: execStderrCap}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	err := cmd.Run()
+	switch {
+	case stdout.truncated:
+		return nil, fmt.Errorf("output exceeds %d bytes", execOutputCap)
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("timed out after %s", execTimeout)
+	case err != nil:
+		var exitErr *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); errors.As(err, &exitErr) && msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// cappedBuffer keeps the first limit bytes written to it and drops the
+// rest, so a chatty command can't grow it without bound. overflow, when
+// set, is called once the limit is passed. The buffer isn't embedded: its
+// ReadFrom would let io.Copy bypass Write.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	overflow  func()
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - b.buf.Len()
+	if len(p) > room && !b.truncated {
+		b.truncated = true
+		if b.overflow != nil {
+			b.overflow()
+		}
+	}
+	if room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	// Report the full length so the command isn't sent a short write
+	return len(p), nil
+}
+
+func (b *cappedBuffer) Bytes() []byte  { return b.buf.Bytes() }
+func (b *cappedBuffer) String() string { return b.buf.String() }
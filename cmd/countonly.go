@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// countOnlyHostLimit is how many down hosts --count-only names before
+// truncating the list
+const countOnlyHostLimit = 5
+
+// countOnlyLine formats the single --count-only summary line, e.g.
+// "netcheck: 47/50 up, 3 down (a, b, c)"
+func countOnlyLine(summary Summary) string {
+	total := summary.Passed + summary.Failed + summary.Errored + summary.Unknown
+	line := fmt.Sprintf("netcheck: %d/%d up", summary.Passed, total)
+	if down := len(summary.FailedHosts); down > 0 {
+		names := summary.FailedHosts
+		if len(names) > countOnlyHostLimit {
+			names = append(names[:countOnlyHostLimit:countOnlyHostLimit], "…")
+		}
+		line += fmt.Sprintf(", %d down (%s)", down, strings.Join(names, ", "))
+	}
+	if summary.Maintenance > 0 {
+		line += fmt.Sprintf(", %d in maintenance", summary.Maintenance)
+	}
+	if summary.LowSeverity > 0 {
+		line += fmt.Sprintf(", %d below min severity", summary.LowSeverity)
+	}
+	if summary.Disabled > 0 {
+		line += fmt.Sprintf(", disabled (%d)", summary.Disabled)
+	}
+	return line
+}
+
+// validateCountOnly rejects modes that would add output around the
+// --count-only line
+func validateCountOnly(outputs []outputTarget) error {
+	if printPlan != "" || tuiMode {
+		return fmt.Errorf("--count-only can't be combined with --print-plan or --tui")
+	}
+	for _, t := range outputs {
+		if t.Format != outputConsole && t.Path == "" {
+			return fmt.Errorf("--count-only prints to stdout; give --output %s a file", t.Format)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// hostDependencies returns the names listed in a host's depends= tokens,
+// which may repeat or hold a comma-separated list
+func hostDependencies(host core.Host) []string {
+	var names []string
+	for _, value := range host.Tokens.Values("depends") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// orderByDependencies returns the hosts reordered so every host runs after
+// the hosts it depends on, otherwise keeping config order. Dependencies are
+// matched by label (name=, or the hostname when unnamed). Unknown or
+// ambiguous names and cycles are reported as config errors.
+func orderByDependencies(hosts []core.Host) ([]core.Host, error) {
+	byName := make(map[string]int, len(hosts))
+	duplicate := map[string]bool{}
+	hasDeps := false
+	for i, host := range hosts {
+		name := host.DisplayName()
+		if _, ok := byName[name]; ok {
+			duplicate[name] = true
+		}
+		byName[name] = i
+		if host.Tokens.Has("depends") {
+			hasDeps = true
+		}
+	}
+	if !hasDeps {
+		return hosts, nil
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(hosts))
+	ordered := make([]core.Host, 0, len(hosts))
+	var path []string
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), hosts[i].DisplayName())
+		}
+		state[i] = visiting
+		path = append(path, hosts[i].DisplayName())
+		for _, name := range hostDependencies(hosts[i]) {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("host %q depends on unknown host %q", hosts[i].DisplayName(), name)
+			}
+			if duplicate[name] {
+				return fmt.Errorf("host %q depends on %q, which names more than one host", hosts[i].DisplayName(), name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = done
+		ordered = append(ordered, hosts[i])
+		return nil
+	}
+
+	for i := range hosts {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// failedDependency returns the first prerequisite of host that didn't pass
+// in the current run, given each executed host's status by label
+func failedDependency(host core.Host, outcomes map[string]core.Status) (string, bool) {
+	for _, name := range hostDependencies(host) {
+		if outcomes[name] != core.StatusPassed {
+			return name, true
+		}
+	}
+	return "", false
+}
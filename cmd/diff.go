@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+var (
+	diffBaseline string
+	diffCurrent  string
+	diffSlower   float64
+	diffMinDelta time.Duration
+)
+
+// diffCmd compares two JSON result files
+var diffCmd = &cobra.Command{
+	Use:   "diff --baseline old.json --current new.json",
+	Short: "Compare two runs' JSON results and list what changed",
+	Long: `Compare two files written by --output json (for example before and after a
+migration) and print a changelog: checks that started failing, checks that
+started passing, checks that got significantly slower, and checks found in
+only one of the files. Checks are matched by ID, so labels may change; with
+--repeat, each check's last result counts.
+
+A check is significantly slower when it passed in both runs and its duration
+grew by more than --slower percent and at least --min-delta. The exit code is
+1 when anything started failing or got slower, so the diff can gate a CI job,
+and 2 when a file can't be read.`,
+	Args: cobra.NoArgs,
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffBaseline, "baseline", "", "JSON results of the earlier run")
+	diffCmd.Flags().StringVar(&diffCurrent, "current", "", "JSON results of the run to compare")
+	diffCmd.Flags().Float64Var(&diffSlower, "slower", 50, "percentage increase in duration that counts as significantly slower")
+	diffCmd.Flags().DurationVar(&diffMinDelta, "min-delta", 10*time.Millisecond, "smallest increase in duration that counts as slower, to ignore jitter on fast checks")
+	diffCmd.MarkFlagRequired("baseline")
+	diffCmd.MarkFlagRequired("current")
+	rootCmd.AddCommand(diffCmd)
+}
+
+// resultsDiff is the changelog between two runs, each list in the current
+// run's order (the baseline's for Missing)
+type resultsDiff struct {
+	NewlyFailing []resultRecord
+	NewlyPassing []resultRecord
+	Slower       [][2]resultRecord
+	Added        []resultRecord
+	Missing      []resultRecord
+}
+
+// regressed reports whether anything got worse
+func (d resultsDiff) regressed() bool {
+	return len(d.NewlyFailing) > 0 || len(d.Slower) > 0
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if diffSlower < 0 {
+		return fmt.Errorf("invalid --slower %g: must not be negative", diffSlower)
+	}
+	cmd.SilenceUsage = true
+
+	// Unreadable files exit 2, so a CI gate can tell them from regressions
+	baseline, err := loadResultsFile(diffBaseline)
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: err}
+	}
+	current, err := loadResultsFile(diffCurrent)
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: err}
+	}
+
+	d := diffResults(baseline, current, diffSlower, diffMinDelta)
+	writeResultsDiff(os.Stdout, d)
+	if d.regressed() {
+		cmd.SilenceErrors = true
+		return &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("%d check(s) newly failing, %d slower", len(d.NewlyFailing), len(d.Slower))}
+	}
+	return nil
+}
+
+// loadResultsFile reads a --output json document, keeping each check's
+// last result in first-seen order
+func loadResultsFile(path string) ([]resultRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Results []resultRecord `json:"results"`
+		Error   string         `json:"error"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s isn't netcheck JSON output: %w", path, err)
+	}
+	if doc.Error != "" {
+		return nil, fmt.Errorf("%s is from a run that failed before any check: %s", path, doc.Error)
+	}
+	if doc.Results == nil {
+		return nil, fmt.Errorf("%s has no results (want a file written by --output json)", path)
+	}
+
+	index := map[string]int{}
+	var records []resultRecord
+	for _, rec := range doc.Results {
+		if i, ok := index[rec.ID]; ok {
+			records[i] = rec
+			continue
+		}
+		index[rec.ID] = len(records)
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// diffResults compares two runs by check ID. Skipped checks have no state
+// to compare, so they only count towards Added and Missing.
+func diffResults(baseline, current []resultRecord, slowerPct float64, minDelta time.Duration) resultsDiff {
+	var d resultsDiff
+	before := make(map[string]resultRecord, len(baseline))
+	for _, rec := range baseline {
+		before[rec.ID] = rec
+	}
+	seen := map[string]bool{}
+	for _, cur := range current {
+		seen[cur.ID] = true
+		old, ok := before[cur.ID]
+		if !ok {
+			d.Added = append(d.Added, cur)
+			continue
+		}
+		if cur.Status == string(core.StatusSkipped) || old.Status == string(core.StatusSkipped) {
+			continue
+		}
+		wasUp := old.Status == string(core.StatusPassed)
+		isUp := cur.Status == string(core.StatusPassed)
+		switch {
+		case wasUp && !isUp:
+			d.NewlyFailing = append(d.NewlyFailing, cur)
+		case !wasUp && isUp:
+			d.NewlyPassing = append(d.NewlyPassing, cur)
+		case wasUp && isUp:
+			delta := cur.DurationMs - old.DurationMs
+			if delta >= durationMs(minDelta) && delta > old.DurationMs*slowerPct/100 {
+				d.Slower = append(d.Slower, [2]resultRecord{old, cur})
+			}
+		}
+	}
+	for _, old := range baseline {
+		if !seen[old.ID] {
+			d.Missing = append(d.Missing, old)
+		}
+	}
+	return d
+}
+
+// writeResultsDiff prints the changelog, one section per kind of change
+func writeResultsDiff(w io.Writer, d resultsDiff) {
+	name := func(rec resultRecord) string {
+		return rec.CheckType + " " + rec.Label
+	}
+	section := func(title string, n int) {
+		fmt.Fprintf(w, "%s (%d):\n", title, n)
+	}
+
+	if len(d.NewlyFailing)+len(d.NewlyPassing)+len(d.Slower)+len(d.Added)+len(d.Missing) == 0 {
+		fmt.Fprintf(w, "No changes between %s and %s\n", diffBaseline, diffCurrent)
+		return
+	}
+	if len(d.NewlyFailing) > 0 {
+		section("Newly failing", len(d.NewlyFailing))
+		for _, rec := range d.NewlyFailing {
+			line := "  " + name(rec) + " [" + rec.Status + "]"
+			if rec.Error != "" {
+				line += ": " + rec.Error
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	if len(d.NewlyPassing) > 0 {
+		section("Newly passing", len(d.NewlyPassing))
+		for _, rec := range d.NewlyPassing {
+			fmt.Fprintln(w, "  "+name(rec))
+		}
+	}
+	if len(d.Slower) > 0 {
+		section("Significantly slower", len(d.Slower))
+		for _, pair := range d.Slower {
+			old, cur := pair[0], pair[1]
+			change := "new"
+			if old.DurationMs > 0 {
+				change = fmt.Sprintf("+%.0f%%", (cur.DurationMs-old.DurationMs)/old.DurationMs*100)
+			}
+			fmt.Fprintf(w, "  %s: %.1fms -> %.1fms (%s)\n", name(cur), old.DurationMs, cur.DurationMs, change)
+		}
+	}
+	for _, part := range []struct {
+		title   string
+		records []resultRecord
+	}{{"New checks", d.Added}, {"Missing checks", d.Missing}} {
+		if len(part.records) == 0 {
+			continue
+		}
+		section(part.title, len(part.records))
+		for _, rec := range part.records {
+			fmt.Fprintln(w, "  "+name(rec)+" ["+rec.Status+"]")
+		}
+	}
+}
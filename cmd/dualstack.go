@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// familyLookupTimeout bounds the lookup deciding whether a dual-stack half
+// has an address to check at all
+const familyLookupTimeout = 5 * time.Second
+
+// missingFamily reports the family ("IPv6") of a dual-stack half whose
+// host has no address in it, so it can be skipped rather than failed.
+// Lookups that fail outright are left for the check to report.
+func missingFamily(host core.Host, opts *core.Options) (string, bool) {
+	if host.DualStack == "" {
+		return "", false
+	}
+	names := hostNames(host)
+	if len(names) == 0 {
+		return "", false
+	}
+	family := host.Tokens.Get("ipv")
+	ctx, cancel := context.WithTimeout(context.Background(), familyLookupTimeout)
+	defer cancel()
+	for _, name := range names {
+		if ok, err := core.HasFamilyAddress(ctx, name, family, opts); ok || err != nil {
+			return "", false
+		}
+	}
+	return "IPv" + family, true
+}
+
+// degradedHosts lists the dual-stack hosts, in run order, where one address
+// family passed and the other failed. A family skipped for having no
+// address doesn't count as failing.
+func degradedHosts(results []core.Result) []string {
+	passed := map[string]map[string]bool{}
+	failed := map[string]map[string]bool{}
+	var order []string
+	for _, r := range results {
+		name := r.Host.DualStack
+		if name == "" {
+			continue
+		}
+		if passed[name] == nil {
+			passed[name], failed[name] = map[string]bool{}, map[string]bool{}
+			order = append(order, name)
+		}
+		family := r.Host.Tokens.Get("ipv")
+		switch r.Status {
+		case core.StatusPassed:
+			passed[name][family] = true
+		case core.StatusFailed, core.StatusErrored:
+			failed[name][family] = true
+		}
+	}
+	var degraded []string
+	for _, name := range order {
+		if passed[name][core.FamilyV4] && failed[name][core.FamilyV6] || passed[name][core.FamilyV6] && failed[name][core.FamilyV4] {
+			degraded = append(degraded, name)
+		}
+	}
+	return degraded
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import "errors"
+
+// Exit codes returned by the netcheck command
+const (
+	ExitOK           = 0
+	ExitChecksFailed = 1
+	ExitConfigError  = 2
+)
+
+// ExitError carries a specific process exit code out of a command
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode maps an error returned by Execute to the process exit code
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitChecksFailed
+}
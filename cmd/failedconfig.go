@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// failedConfigHosts picks the hosts to write to --failed-config: every host
+// that failed or errored in any run, plus the hosts they depend on (which
+// may have passed) so the retry config loads. Hosts keep run order.
+func failedConfigHosts(hosts []core.Host, results []core.Result) []core.Host {
+	failed := map[string]bool{}
+	for _, r := range results {
+		if r.Status == core.StatusFailed || r.Status == core.StatusErrored {
+			failed[r.Host.ID()] = true
+		}
+	}
+
+	byName := make(map[string]core.Host, len(hosts))
+	for _, host := range hosts {
+		byName[host.DisplayName()] = host
+	}
+	keep := map[string]bool{}
+	var need func(host core.Host)
+	need = func(host core.Host) {
+		if keep[host.ID()] {
+			return
+		}
+		keep[host.ID()] = true
+		for _, name := range hostDependencies(host) {
+			if dep, ok := byName[name]; ok {
+				need(dep)
+			}
+		}
+	}
+	for _, host := range hosts {
+		if failed[host.ID()] {
+			need(host)
+		}
+	}
+
+	var picked []core.Host
+	for _, host := range hosts {
+		if keep[host.ID()] {
+			picked = append(picked, host)
+		}
+	}
+	return picked
+}
+
+// writeFailedConfig writes the failing hosts as a text config that can be
+// run with -f. Hosts from text configs keep their line, the comments above
+// it, and the @defaults that applied to it, as written; hosts from YAML and
+// JSON configs get an equivalent line.
+func writeFailedConfig(path string, hosts []core.Host, results []core.Result) error {
+	picked := failedConfigHosts(hosts, results)
+	return writeFileAtomic(path, func(w io.Writer) error {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s netcheck --failed-config: %d check(s) that failed in %s at %s\n", commentChar, len(picked), cfgFile, time.Now().Format(time.RFC3339))
+
+		// @defaults first: they apply wherever they appear, but reading
+		// them up front is clearer
+		var defaults []string
+		for _, host := range picked {
+			if host.Source == nil {
+				continue
+			}
+			for _, line := range host.Source.Defaults {
+				if !slices.Contains(defaults, line) {
+					defaults = append(defaults, line)
+				}
+			}
+		}
+		for _, line := range defaults {
+			b.WriteString(line + "\n")
+		}
+
+		// Hosts expanded from one combined line share its source
+		written := map[*core.HostSource]bool{}
+		for _, host := range picked {
+			if host.Source != nil && written[host.Source] {
+				continue
+			}
+			b.WriteString("\n")
+			if host.Source == nil {
+				b.WriteString(hostConfigLine(host) + "\n")
+				continue
+			}
+			written[host.Source] = true
+			for _, comment := range host.Source.Comments {
+				b.WriteString(comment + "\n")
+			}
+			b.WriteString(host.Source.Line + "\n")
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	})
+}
+
+// hostConfigLine formats a host parsed from a structured config as a text
+// config line, quoting token values the text parser would otherwise split
+func hostConfigLine(host core.Host) string {
+	sep := " "
+	if fieldSep != 0 {
+		sep = string(fieldSep)
+	}
+	fields := []string{host.CheckType, host.HostName}
+	if host.Disabled {
+		fields[0] = "!" + fields[0]
+	}
+	keys := make([]string, 0, len(host.Tokens))
+	for key := range host.Tokens {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, value := range host.Tokens.Values(key) {
+			fields = append(fields, key+"="+quoteConfigValue(value))
+		}
+	}
+	if host.Label != "" {
+		fields = append(fields, "name="+quoteConfigValue(host.Label))
+	}
+	return strings.Join(fields, sep)
+}
+
+// quoteConfigValue quotes a token value containing separators or quotes:
+// double quotes, or single quotes when the value has double quotes in it
+func quoteConfigValue(value string) string {
+	if !strings.ContainsAny(value, " \t\"'") && !strings.ContainsRune(value, fieldSep) && !strings.HasPrefix(value, commentChar) {
+		return value
+	}
+	if strings.Contains(value, `"`) {
+		return "'" + value + "'"
+	}
+	return `"` + value + `"`
+}
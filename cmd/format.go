@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// Formatter renders a finished run for one --output format. Format gets
+// every result, in --sort order, and the summary once all checks are done;
+// w is the target's file, or stdout.
+type Formatter interface {
+	Format(w io.Writer, results []core.Result, summary Summary) error
+}
+
+// FormatterFunc adapts a function to Formatter
+type FormatterFunc func(w io.Writer, results []core.Result, summary Summary) error
+
+// Format implements Formatter
+func (f FormatterFunc) Format(w io.Writer, results []core.Result, summary Summary) error {
+	return f(w, results, summary)
+}
+
+// ResultStreamer is implemented by formatters that also write each result
+// the moment its check completes (ndjson). Their Format still runs at the
+// end and may write nothing.
+type ResultStreamer interface {
+	FormatResult(w io.Writer, result core.Result) error
+}
+
+// ErrorFormatter is implemented by formatters that can report a run that
+// stopped before any check ran (a bad config), in place of results
+type ErrorFormatter interface {
+	FormatError(w io.Writer, kind string, err error) error
+}
+
+var (
+	formattersMu sync.Mutex
+	formatters   = map[string]Formatter{}
+)
+
+// RegisterFormatter makes a formatter available to --output under name
+// (matched case-insensitively). Registering an existing name replaces it,
+// built-ins included. Programs embedding netcheck call it before Execute.
+func RegisterFormatter(name string, f Formatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	formatters[strings.ToLower(name)] = f
+}
+
+// lookupFormatter returns the formatter registered under name
+func lookupFormatter(name string) (Formatter, bool) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	f, ok := formatters[strings.ToLower(name)]
+	return f, ok
+}
+
+// formatterNames lists the registered formats, sorted, for error messages
+func formatterNames() []string {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func init() {
+	// Console output is the log itself, so there is nothing to render
+	RegisterFormatter(outputConsole, FormatterFunc(func(io.Writer, []core.Result, Summary) error { return nil }))
+	RegisterFormatter(outputJSON, jsonFormatter{})
+	RegisterFormatter(outputNDJSON, ndjsonFormatter{})
+	RegisterFormatter(outputJUnit, FormatterFunc(func(w io.Writer, results []core.Result, _ Summary) error {
+		return writeJUnit(w, results)
+	}))
+	RegisterFormatter(outputHTML, FormatterFunc(func(w io.Writer, results []core.Result, summary Summary) error {
+		return writeHTML(w, cfgFile, results, summary)
+	}))
+	RegisterFormatter(outputCSV, FormatterFunc(writeCSV))
+}
+
+// jsonFormatter writes one JSON document with every result, the summary,
+// and any --repeat aggregates
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(w io.Writer, results []core.Result, summary Summary) error {
+	return writeJSON(w, results, summary)
+}
+
+func (jsonFormatter) FormatError(w io.Writer, kind string, err error) error {
+	return writeError(w, kind, err)
+}
+
+// ndjsonFormatter writes one JSON object per check as each completes
+type ndjsonFormatter struct{}
+
+// Format writes nothing: every result was already streamed
+func (ndjsonFormatter) Format(io.Writer, []core.Result, Summary) error {
+	return nil
+}
+
+func (ndjsonFormatter) FormatResult(w io.Writer, result core.Result) error {
+	return writeNDJSON(w, result)
+}
+
+func (ndjsonFormatter) FormatError(w io.Writer, kind string, err error) error {
+	return writeError(w, kind, err)
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// gatewayDetectTimeout bounds the route command that finds the gateway
+const gatewayDetectTimeout = 5 * time.Second
+
+// gatewayPings is how many pings the gateway gets; one reply is enough
+const gatewayPings = "3"
+
+// errNoDefaultRoute means the routing table has no IPv4 default route,
+// which is itself a local network problem
+var errNoDefaultRoute = errors.New("no default route")
+
+// checkGateway pings the --gateway address, or the detected default
+// gateway, before any host is checked. A gateway that doesn't answer (or a
+// missing default route) means the local network is down, so the run is
+// aborted with one message instead of a failure per host.
+func checkGateway(opts *core.Options) error {
+	gateway := gatewayAddr
+	if gateway == "" {
+		var err error
+		if gateway, err = defaultGateway(); errors.Is(err, errNoDefaultRoute) {
+			log.Error().Msg("local network down: no default route")
+			return &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("local network down: %w", err)}
+		} else if err != nil {
+			log.Error().Err(err).Msg("couldn't detect the default gateway; set --gateway")
+			return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("detect default gateway: %w (set --gateway)", err)}
+		}
+	}
+
+	gwLog := log.With().Str("gateway", gateway).Logger()
+	host := core.Host{HostName: gateway, CheckType: "ICMP", Label: "gateway", Tokens: core.Tokens{"count": {gatewayPings}}}
+	passed, err := core.CallCheck(context.Background(), core.CheckTypes["ICMP"], host, opts)
+	if err == nil && !passed {
+		err = errors.New("no reply to ping")
+	}
+	if err != nil {
+		gwLog.Error().Err(err).Msg("local network down: gateway unreachable, not checking any hosts")
+		return &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("local network down: gateway %s unreachable: %w", gateway, err)}
+	}
+	gwLog.Info().Msg("gateway reachable")
+	return nil
+}
+
+// defaultGateway finds the IPv4 default gateway: from /proc/net/route on
+// Linux, `route -n get default` on macOS and the BSDs, and `route print`
+// on Windows
+func defaultGateway() (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/proc/net/route")
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		return parseProcRoute(f)
+	case "windows":
+		out, err := routeOutput("route", "print", "-4", "0.0.0.0")
+		if err != nil {
+			return "", err
+		}
+		return parseRoutePrint(out)
+	default:
+		out, err := routeOutput("route", "-n", "get", "default")
+		if err != nil {
+			// route exits non-zero when there is no default route
+			if strings.Contains(out, "not in table") {
+				return "", errNoDefaultRoute
+			}
+			return "", err
+		}
+		return parseRouteGet(out)
+	}
+}
+
+// routeOutput runs a route command and returns its output
+func routeOutput(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayDetectTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("%s: %w", name, err)
+	}
+	return string(out), nil
+}
+
+// parseProcRoute reads the default route from /proc/net/route, where
+// addresses are little-endian hex and flag 0x2 marks a gateway route
+func parseProcRoute(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[1] != "00000000" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&0x3 != 0x3 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil {
+			continue
+		}
+		return net.IPv4(byte(v), byte(v>>8), byte(v>>16), byte(v>>24)).String(), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errNoDefaultRoute
+}
+
+// parseRouteGet reads "gateway: 192.168.1.1" from `route -n get default`
+func parseRouteGet(out string) (string, error) {
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && key == "gateway" {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	return "", errNoDefaultRoute
+}
+
+// parseRoutePrint reads the gateway of the 0.0.0.0/0 row of `route print`,
+// skipping on-link rows
+func parseRoutePrint(out string) (string, error) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "0.0.0.0" && fields[1] == "0.0.0.0" && net.ParseIP(fields[2]) != nil {
+			return fields[2], nil
+		}
+	}
+	return "", errNoDefaultRoute
+}
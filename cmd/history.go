@@ -0,0 +1,220 @@
+//go:build history
+
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	_ "modernc.org/sqlite"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// historySchema creates the results table on first use
+const historySchema = `CREATE TABLE IF NOT EXISTS results (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id      TEXT    NOT NULL,
+	timestamp   TEXT    NOT NULL,
+	host        TEXT    NOT NULL,
+	type        TEXT    NOT NULL,
+	passed      INTEGER NOT NULL,
+	duration_ms REAL    NOT NULL,
+	error       TEXT    NOT NULL DEFAULT '',
+	check_id    TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS results_host_timestamp ON results (host, timestamp);`
+
+// historyMigrations bring files written by older versions up to the
+// current schema; each runs only when its column is missing
+var historyMigrations = []struct{ column, stmt string }{
+	{"check_id", `ALTER TABLE results ADD COLUMN check_id TEXT NOT NULL DEFAULT ''`},
+}
+
+// historyIndexes are created after migrations since they may use new columns
+const historyIndexes = `CREATE INDEX IF NOT EXISTS results_check_id_timestamp ON results (check_id, timestamp);`
+
+var (
+	historyPath  string
+	historyDB    string
+	historyHost  string
+	historyID    string
+	historyLimit int
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show recent results recorded with --history",
+	Long: `Query the SQLite history file written by 'netcheck --history path.db',
+newest results first.`,
+	Args: cobra.NoArgs,
+	RunE: showHistory,
+}
+
+func init() {
+	rootCmd.Flags().StringVar(&historyPath, "history", "", "append every result to this SQLite history file")
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringVarP(&historyDB, "db", "d", "", "SQLite history file to query (required)")
+	historyCmd.Flags().StringVar(&historyHost, "host", "", "only show results for this host")
+	historyCmd.Flags().StringVar(&historyID, "id", "", "only show results for this check ID")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "number of results to show")
+	historyCmd.MarkFlagRequired("db")
+
+	core.RegisterResultStore("history", historyStore{})
+}
+
+// openHistory opens (creating if needed) a history file
+func openHistory(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init history %s: %w", path, err)
+	}
+	if err := migrateHistory(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate history %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// migrateHistory adds columns missing from older history files
+func migrateHistory(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('results')`)
+	if err != nil {
+		return err
+	}
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range historyMigrations {
+		if columns[m.column] {
+			continue
+		}
+		if _, err := db.Exec(m.stmt); err != nil {
+			return err
+		}
+	}
+	_, err = db.Exec(historyIndexes)
+	return err
+}
+
+// historyStore records results to the --history file, if one is set
+type historyStore struct{}
+
+// Save inserts one row per executed check, sharing a run ID
+func (historyStore) Save(ctx context.Context, results []core.Result) error {
+	if historyPath == "" {
+		return nil
+	}
+	runStarted := core.RunStarted(ctx)
+	if runStarted.IsZero() {
+		runStarted = time.Now()
+	}
+	db, err := openHistory(historyPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO results (run_id, timestamp, host, type, passed, duration_ms, error, check_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	runID := runStarted.UTC().Format("20060102T150405.000000000Z")
+	for _, r := range results {
+		if r.Status == core.StatusSkipped {
+			continue
+		}
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+		}
+		_, err := stmt.Exec(runID, r.Started.UTC().Format(time.RFC3339Nano), r.Host.HostName, r.Host.CheckType, r.Passed(), durationMs(r.Duration), errText, r.Host.ID())
+		if err != nil {
+			return fmt.Errorf("record history: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func showHistory(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(historyDB); err != nil {
+		return err
+	}
+	db, err := openHistory(historyDB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	query := `SELECT timestamp, check_id, host, type, passed, duration_ms, error FROM results`
+	var where []string
+	var queryArgs []any
+	if historyHost != "" {
+		where = append(where, `host = ?`)
+		queryArgs = append(queryArgs, historyHost)
+	}
+	if historyID != "" {
+		where = append(where, `check_id = ?`)
+		queryArgs = append(queryArgs, historyID)
+	}
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY timestamp DESC LIMIT ?`
+	queryArgs = append(queryArgs, historyLimit)
+
+	rows, err := db.Query(query, queryArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIMESTAMP\tID\tHOST\tTYPE\tRESULT\tDURATION\tERROR")
+	for rows.Next() {
+		var timestamp, checkID, host, checkType, errText string
+		var passed bool
+		var duration float64
+		if err := rows.Scan(&timestamp, &checkID, &host, &checkType, &passed, &duration, &errText); err != nil {
+			return err
+		}
+		result := "FAIL"
+		if passed {
+			result = "PASS"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.1fms\t%s\n", timestamp, checkID, host, checkType, result, duration, errText)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return tw.Flush()
+}
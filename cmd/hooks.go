@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// Host states passed to --on-change hooks as {state}
+const (
+	stateUp   = "up"
+	stateDown = "down"
+)
+
+// changeHook runs a command when a host changes state between runs
+type changeHook struct {
+	args    []string
+	timeout time.Duration
+	states  map[string]string // check ID -> last state
+}
+
+// newChangeHook parses an --on-change command line. Arguments are split
+// like config fields (quotes group words) and never go through a shell.
+func newChangeHook(command string, timeout time.Duration) (*changeHook, error) {
+	args, err := splitFields(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --on-change: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid --on-change: empty command")
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("invalid --on-change-timeout %s: must be positive", timeout)
+	}
+	return &changeHook{args: args, timeout: timeout, states: map[string]string{}}, nil
+}
+
+// resultState maps a result to up/down; skipped hosts have no state
+func resultState(r core.Result) (string, bool) {
+	switch r.Status {
+	case core.StatusPassed:
+		return stateUp, true
+	case core.StatusSkipped:
+		return "", false
+	default:
+		return stateDown, true
+	}
+}
+
+// Observe records a result and runs the hook if the host's state differs
+// from its previous run. The first result for a host only sets a baseline.
+func (h *changeHook) Observe(r core.Result) {
+	state, ok := resultState(r)
+	if !ok {
+		return
+	}
+	// Keyed by check ID: ICMP+HTTP lines give several checks one label
+	id := r.Host.ID()
+	prev, seen := h.states[id]
+	h.states[id] = state
+	if !seen || prev == state {
+		return
+	}
+	h.run(r, prev, state)
+}
+
+// run executes the hook with placeholders substituted and logs its output
+func (h *changeHook) run(r core.Result, prev, state string) {
+	errText := ""
+	if r.Err != nil {
+		errText = r.Err.Error()
+	}
+	replacer := strings.NewReplacer(
+		"{host}", r.Host.HostName,
+		"{label}", r.Host.DisplayName(),
+		"{type}", r.Host.CheckType,
+		"{state}", state,
+		"{prev}", prev,
+		"{error}", errText,
+	)
+	args := make([]string, len(h.args))
+	for i, arg := range h.args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+
+	hookLog := hostLogger(r.Host, checkLabelFor(r.Host.CheckType)).With().
+		Str("from", prev).Str("to", state).Str("output", strings.TrimSpace(string(output))).Logger()
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		hookLog.Error().Dur("timeout", h.timeout).Msg("on-change hook timed out")
+	case err != nil:
+		hookLog.Error().Err(err).Msg("on-change hook failed")
+	default:
+		hookLog.Info().Msg("on-change hook ran")
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// maxRangeHosts caps how many hosts one hostname's ranges may expand to, so
+// a typo like web[1-100000] is a config error rather than a runaway run
+const maxRangeHosts = 1000
+
+// reRangeGroup matches an innermost bracket group in a hostname
+var reRangeGroup = regexp.MustCompile(`\[([^\[\]]*)\]`)
+
+var (
+	reNumericRange = regexp.MustCompile(`^([0-9]+)-([0-9]+)$`)
+	reAlphaRange   = regexp.MustCompile(`^([a-zA-Z])-([a-zA-Z])$`)
+	reRangeItem    = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+)
+
+// rangeName is one expansion of a hostname and the values substituted for
+// its bracket groups, in order
+type rangeName struct {
+	name   string
+	values []string
+}
+
+// expandHostRanges replaces each host whose hostname has bracket ranges
+// (web[01-20].example.com, web[a,b,c], db[a-c][1-2]) with one host per
+// expansion. Several groups, or groups nested inside one another, expand
+// as a cross-product. Brackets that aren't a range, like an IPv6 literal in
+// a URL, are left alone. Expanded hosts get distinct labels and IDs (a
+// name= or id= gains the substituted values) and, from a text config, a
+// config line of their own so --failed-config writes only the ones that
+// failed.
+func expandHostRanges(hosts []core.Host) ([]core.Host, error) {
+	expanded := make([]core.Host, 0, len(hosts))
+	for _, host := range hosts {
+		names, err := expandRanges(host.HostName)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 1 && names[0].values == nil {
+			expanded = append(expanded, host)
+			continue
+		}
+		for _, n := range names {
+			suffix := strings.Join(n.values, "-")
+			h := host
+			h.HostName = n.name
+			h.Tokens = maps.Clone(host.Tokens)
+			if id := host.Tokens.Get("id"); id != "" {
+				h.Tokens["id"] = []string{id + "-" + suffix}
+			}
+			if host.Label != "" {
+				h.Label = fmt.Sprintf("%s (%s)", host.Label, suffix)
+			}
+			if host.Source != nil {
+				h.Source = &core.HostSource{Line: hostConfigLine(h), Comments: host.Source.Comments}
+			}
+			expanded = append(expanded, h)
+		}
+	}
+	return expanded, nil
+}
+
+// expandRanges expands the bracket ranges in name, innermost and leftmost
+// first, dropping repeats. A name without ranges comes back as is.
+func expandRanges(name string) ([]rangeName, error) {
+	names := []rangeName{{name: name}}
+	for {
+		var next []rangeName
+		seen := map[string]bool{}
+		grew := false
+		for _, n := range names {
+			start, end, values, err := firstRange(n.name)
+			if err != nil {
+				return nil, err
+			}
+			if values == nil {
+				next = append(next, n)
+				seen[n.name] = true
+				continue
+			}
+			grew = true
+			for _, v := range values {
+				expanded := n.name[:start] + v + n.name[end:]
+				if seen[expanded] {
+					continue
+				}
+				seen[expanded] = true
+				next = append(next, rangeName{name: expanded, values: append(append([]string(nil), n.values...), v)})
+				if len(next) > maxRangeHosts {
+					return nil, fmt.Errorf("%s expands to more than %d hosts", name, maxRangeHosts)
+				}
+			}
+		}
+		names = next
+		if !grew {
+			return names, nil
+		}
+	}
+}
+
+// firstRange finds the first bracket group in name that is a range,
+// returning its span and values; nil values when there's none
+func firstRange(name string) (int, int, []string, error) {
+	for _, m := range reRangeGroup.FindAllStringSubmatchIndex(name, -1) {
+		values, err := rangeValues(name[m[2]:m[3]])
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("invalid range %s in %s: %w", name[m[0]:m[1]], name, err)
+		}
+		if values != nil {
+			return m[0], m[1], values, nil
+		}
+	}
+	return 0, 0, nil, nil
+}
+
+// rangeValues expands the inside of a bracket group: a numeric range (01-20,
+// zero-padded to the start's width when it has a leading zero), a letter
+// range (a-f), or a comma list mixing those with plain items (a,b,c or
+// 1-3,7). Nil without an error means the group isn't a range, e.g. "::1".
+func rangeValues(spec string) ([]string, error) {
+	items := strings.Split(spec, ",")
+	if len(items) == 1 && !reNumericRange.MatchString(spec) && !reAlphaRange.MatchString(spec) {
+		return nil, nil
+	}
+	var values []string
+	for _, item := range items {
+		switch {
+		case item == "":
+			return nil, fmt.Errorf("empty item")
+		case reNumericRange.MatchString(item):
+			m := reNumericRange.FindStringSubmatch(item)
+			lo, err1 := strconv.Atoi(m[1])
+			hi, err2 := strconv.Atoi(m[2])
+			if err1 != nil || err2 != nil || hi-lo >= maxRangeHosts {
+				return nil, fmt.Errorf("%s has more than %d values", item, maxRangeHosts)
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("%s runs backwards", item)
+			}
+			width := 0
+			if len(m[1]) > 1 && m[1][0] == '0' {
+				width = len(m[1])
+			}
+			for i := lo; i <= hi; i++ {
+				values = append(values, fmt.Sprintf("%0*d", width, i))
+			}
+		case reAlphaRange.MatchString(item):
+			lo, hi := item[0], item[2]
+			if (lo >= 'a') != (hi >= 'a') {
+				return nil, fmt.Errorf("%s mixes upper and lower case", item)
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("%s runs backwards", item)
+			}
+			for c := lo; c <= hi; c++ {
+				values = append(values, string(c))
+			}
+		case reRangeItem.MatchString(item):
+			values = append(values, item)
+		default:
+			// Not range syntax, so the brackets are literal
+			return nil, nil
+		}
+	}
+	return values, nil
+}
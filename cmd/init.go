@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed init_templates
+var initTemplates embed.FS
+
+var forceInit bool
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init [config-path]",
+	Short: "Generate a starter config and example scripts",
+	Long: `Generate a commented starter configuration demonstrating each check type,
+plus a sample Lua and Python script in the scripts folder.
+
+The config is written to netcheck.txt unless a path is given. Existing files
+are left untouched unless --force is specified.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInit,
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().BoolVar(&forceInit, "force", false, "overwrite existing files")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	configPath := "netcheck.txt"
+	if len(args) == 1 {
+		configPath = args[0]
+	}
+
+	// Template file -> destination path
+	files := []struct {
+		template string
+		dest     string
+		mode     os.FileMode
+	}{
+		{"init_templates/netcheck.txt", configPath, 0644},
+		{"init_templates/starter.lua", filepath.Join("scripts", "starter.lua"), 0644},
+		{"init_templates/starter.py", filepath.Join("scripts", "starter.py"), 0755},
+	}
+
+	// Refuse to overwrite anything before writing so a partial init can't happen
+	if !forceInit {
+		for _, f := range files {
+			if _, err := os.Stat(f.dest); err == nil {
+				return fmt.Errorf("%s already exists (use --force to overwrite)", f.dest)
+			}
+		}
+	}
+
+	for _, f := range files {
+		data, err := initTemplates.ReadFile(f.template)
+		if err != nil {
+			return fmt.Errorf("read template %s: %w", f.template, err)
+		}
+		if dir := filepath.Dir(f.dest); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("create %s: %w", dir, err)
+			}
+		}
+		if err := os.WriteFile(f.dest, data, f.mode); err != nil {
+			return fmt.Errorf("write %s: %w", f.dest, err)
+		}
+		fmt.Printf("✓ Wrote %s\n", f.dest)
+	}
+
+	fmt.Println()
+	fmt.Printf("Run the checks with: netcheck -f %s\n", configPath)
+	return nil
+}
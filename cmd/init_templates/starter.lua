@@ -0,0 +1,13 @@
+-- Starter Lua check generated by "netcheck init"
+--
+-- netcheck sets the global 'hostname' before running this script.
+-- Set 'result' to true (pass) or false (fail), and optionally
+-- 'error_message' to explain a failure.
+
+if hostname == nil or hostname == "" then
+    result = false
+    error_message = "no hostname provided"
+else
+    -- Replace this with a real check for your service
+    result = true
+end
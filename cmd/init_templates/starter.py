@@ -0,0 +1,31 @@
+#!/usr/bin/env python3
+"""
+Starter Python check generated by "netcheck init"
+
+netcheck passes the hostname as sys.argv[1]. Exit with code 0 to pass,
+or non-zero to fail after printing the reason to stderr.
+"""
+
+import socket
+import sys
+
+
+def main():
+    if len(sys.argv) < 2:
+        print("Error: No hostname provided", file=sys.stderr)
+        sys.exit(1)
+
+    # Accept host or host:port (default port 80)
+    host, _, port = sys.argv[1].partition(":")
+    port = int(port) if port else 80
+
+    try:
+        with socket.create_connection((host, port), timeout=5):
+            sys.exit(0)
+    except OSError as e:
+        print(f"Connection to {host}:{port} failed: {e}", file=sys.stderr)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -92,7 +92,7 @@ func installUV(cmd *cobra.Command, args []string) error {
 			fmt.Println("⚠ Warning: UV installation completed but verification failed")
 			fmt.Println("  You may need to restart your terminal or add UV to your PATH")
 			fmt.Println("  Default UV location:")
-			fmt.Println("    - Windows: %USERPROFILE%\\.cargo\\bin\\uv.exe")
+			fmt.Printf("    - Windows: %s\n", `%USERPROFILE%\.cargo\bin\uv.exe`)
 			fmt.Println("    - macOS/Linux: ~/.cargo/bin/uv")
 		}
 	}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// listChecksCmd represents the list-checks command
+var listChecksCmd = &cobra.Command{
+	Use:   "list-checks",
+	Short: "List the available check types and their aliases",
+	Long: `List every check type code netcheck understands, its display name, and any
+aliases (built-in or added with --alias) that resolve to it, and the port used
+when a host doesn't give one (after any --default-port overrides).`,
+	Args: cobra.NoArgs,
+	RunE: listChecks,
+}
+
+func init() {
+	rootCmd.AddCommand(listChecksCmd)
+}
+
+func listChecks(cmd *cobra.Command, args []string) error {
+	codes := make([]string, 0, len(core.CheckTypes))
+	for code := range core.CheckTypes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	// Group aliases under their canonical code
+	aliases := map[string][]string{}
+	for alias, code := range core.CheckTypeAliases {
+		aliases[code] = append(aliases[code], alias)
+	}
+
+	fmt.Printf("%-6s %-26s %-5s %s\n", "CODE", "NAME", "PORT", "ALIASES")
+	for _, code := range codes {
+		names := aliases[code]
+		sort.Strings(names)
+		port := "-"
+		if p, ok := core.DefaultPorts[code]; ok {
+			port = strconv.Itoa(p)
+		}
+		fmt.Printf("%-6s %-26s %-5s %s\n", code, checkLabelFor(code), port, strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// maintenanceWindow is a recurring weekly window such as "Sat 02:00-04:00
+// Europe/London". Windows whose end is before their start run past midnight.
+type maintenanceWindow struct {
+	days       [7]bool // indexed by time.Weekday; all false means every day
+	start, end int     // minutes after midnight
+	loc        *time.Location
+}
+
+// maintenanceWindows are the global --maintenance windows
+var maintenanceWindows []maintenanceWindow
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseMaintenanceWindow parses "[DAYS] HH:MM-HH:MM [TZ]". DAYS is a day
+// (Sat), a range (Mon-Fri), or a comma list of either; without it the
+// window repeats daily. TZ is an IANA zone name and defaults to local time.
+func parseMaintenanceWindow(spec string) (maintenanceWindow, error) {
+	w := maintenanceWindow{loc: time.Local}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 3 {
+		return w, fmt.Errorf("invalid maintenance window %q: want '[DAYS] HH:MM-HH:MM [TZ]'", spec)
+	}
+
+	// The time range is the only field with a colon
+	idx := -1
+	for i, f := range fields {
+		if strings.Contains(f, ":") {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 || idx > 1 || len(fields)-idx > 2 {
+		return w, fmt.Errorf("invalid maintenance window %q: want '[DAYS] HH:MM-HH:MM [TZ]'", spec)
+	}
+	if idx == 1 {
+		if err := w.parseDays(fields[0]); err != nil {
+			return w, fmt.Errorf("invalid maintenance window %q: %w", spec, err)
+		}
+	}
+	from, to, ok := strings.Cut(fields[idx], "-")
+	var err error
+	if ok {
+		if w.start, err = parseClock(from); err == nil {
+			w.end, err = parseClock(to)
+		}
+	}
+	if !ok || err != nil {
+		return w, fmt.Errorf("invalid maintenance window %q: time range must be HH:MM-HH:MM", spec)
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("invalid maintenance window %q: start and end are the same", spec)
+	}
+	if idx+1 < len(fields) {
+		if w.loc, err = time.LoadLocation(fields[idx+1]); err != nil {
+			return w, fmt.Errorf("invalid maintenance window %q: unknown time zone %s", spec, fields[idx+1])
+		}
+	}
+	return w, nil
+}
+
+// parseDays sets the window's days from "Sat", "Mon-Fri", or "Sat,Sun"
+func (w *maintenanceWindow) parseDays(spec string) error {
+	for _, part := range strings.Split(strings.ToLower(spec), ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		from, ok := weekdays[first]
+		to := from
+		if isRange {
+			var lastOK bool
+			to, lastOK = weekdays[last]
+			ok = ok && lastOK
+		}
+		if !ok {
+			return fmt.Errorf("unknown day %q (use Mon, Tue, ... Sun)", part)
+		}
+		// Ranges may wrap around the week (Fri-Mon)
+		for d := from; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	hours, herr := strconv.Atoi(h)
+	minutes, merr := strconv.Atoi(m)
+	if !ok || herr != nil || merr != nil || hours < 0 || hours > 24 || minutes < 0 || minutes > 59 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hours*60 + minutes, nil
+}
+
+// onDay reports whether the window opens on day
+func (w maintenanceWindow) onDay(day time.Weekday) bool {
+	return w.days == [7]bool{} || w.days[day]
+}
+
+// Contains reports whether t falls inside the window
+func (w maintenanceWindow) Contains(t time.Time) bool {
+	t = t.In(w.loc)
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return w.onDay(t.Weekday()) && minute >= w.start && minute < w.end
+	}
+	// Past midnight: the late part opens today, the early part opened yesterday
+	yesterday := (t.Weekday() + 6) % 7
+	return (w.onDay(t.Weekday()) && minute >= w.start) || (w.onDay(yesterday) && minute < w.end)
+}
+
+// parseMaintenanceFlags parses the --maintenance windows
+func parseMaintenanceFlags(specs []string) ([]maintenanceWindow, error) {
+	windows := make([]maintenanceWindow, 0, len(specs))
+	for _, spec := range specs {
+		w, err := parseMaintenanceWindow(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --maintenance: %w", err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// validateMaintenanceTokens checks every host's maint= windows so a typo is
+// a config error rather than a surprise at check time
+func validateMaintenanceTokens(hosts []core.Host) error {
+	for _, host := range hosts {
+		for _, spec := range host.Tokens.Values("maint") {
+			if _, err := parseMaintenanceWindow(spec); err != nil {
+				return fmt.Errorf("host %s: %w", host.DisplayName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// inMaintenance reports whether t falls in a global window or one of the
+// host's maint= windows
+func inMaintenance(host core.Host, t time.Time) bool {
+	for _, w := range maintenanceWindows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	for _, spec := range host.Tokens.Values("maint") {
+		if w, err := parseMaintenanceWindow(spec); err == nil && w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// metricsStore writes the run's results to --metrics-file in the Prometheus
+// text exposition format, for node_exporter's textfile collector
+type metricsStore struct{}
+
+// Save rewrites the metrics file. With --repeat the last run of each check
+// wins, so the file always reflects the latest state.
+func (metricsStore) Save(ctx context.Context, results []core.Result) error {
+	if metricsFile == "" {
+		return nil
+	}
+	runStarted := core.RunStarted(ctx)
+	if runStarted.IsZero() {
+		runStarted = time.Now()
+	}
+	return writeFileAtomic(metricsFile, func(w io.Writer) error {
+		return writeMetrics(w, runStarted, results)
+	})
+}
+
+// writeMetrics renders one sample per check (by check ID) plus run-wide
+// gauges. Skipped checks have no sample since they didn't run.
+func writeMetrics(w io.Writer, runStarted time.Time, results []core.Result) error {
+	latest := map[string]core.Result{}
+	var order []string
+	for _, r := range results {
+		if r.Status == core.StatusSkipped {
+			continue
+		}
+		id := r.Host.ID()
+		if _, seen := latest[id]; !seen {
+			order = append(order, id)
+		}
+		latest[id] = r
+	}
+	last := make([]core.Result, 0, len(order))
+	for _, id := range order {
+		last = append(last, latest[id])
+	}
+	summary := summarize(last)
+
+	var b strings.Builder
+	gauge := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	gauge("netcheck_check_up", "Whether the check passed (1) or not (0).")
+	for _, r := range last {
+		up := 0
+		if r.Passed() {
+			up = 1
+		}
+		fmt.Fprintf(&b, "netcheck_check_up{%s} %d\n", metricLabels(r), up)
+	}
+	gauge("netcheck_check_duration_seconds", "How long the check took.")
+	for _, r := range last {
+		fmt.Fprintf(&b, "netcheck_check_duration_seconds{%s} %g\n", metricLabels(r), r.Duration.Seconds())
+	}
+	gauge("netcheck_checks", "Checks in the last run by status.")
+	for _, s := range []struct {
+		status string
+		n      int
+	}{{"passed", summary.Passed}, {"failed", summary.Failed}, {"error", summary.Errored}, {"unknown", summary.Unknown}, {"maintenance", summary.Maintenance}, {"low_severity", summary.LowSeverity}} {
+		fmt.Fprintf(&b, "netcheck_checks{status=%q} %d\n", s.status, s.n)
+	}
+	gauge("netcheck_last_run_timestamp_seconds", "Unix time the last run started.")
+	fmt.Fprintf(&b, "netcheck_last_run_timestamp_seconds %d\n", runStarted.Unix())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// metricLabels renders a check's identifying labels
+func metricLabels(r core.Result) string {
+	return fmt.Sprintf(`id="%s",host="%s",label="%s",type="%s"`,
+		escapeLabel(r.Host.ID()), escapeLabel(r.Host.HostName), escapeLabel(r.Host.DisplayName()), escapeLabel(r.Host.CheckType))
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+// writeFileAtomic writes path through a temp file in the same directory and
+// renames it into place, so readers never see a partial file. The temp name
+// doesn't end in .prom, which the textfile collector would pick up.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+//go:embed notify_templates
+var notifyTemplates embed.FS
+
+// defaultNotifyTemplate is used when --notify-url is set without a template
+const defaultNotifyTemplate = "generic"
+
+// notifyTimeout bounds the notification request
+const notifyTimeout = 10 * time.Second
+
+// notifyData is what notification templates render
+type notifyData struct {
+	Config  string
+	Results []core.Result
+	Summary summaryRecord
+}
+
+// newNotifyData builds the template data. Failures inside a maintenance
+// window are left out so planned work doesn't page anyone, as are failures
+// below --min-severity.
+func newNotifyData(config string, results []core.Result) notifyData {
+	alerting := make([]core.Result, 0, len(results))
+	for _, r := range results {
+		if (r.Maintenance || r.LowSeverity) && !r.Passed() {
+			continue
+		}
+		alerting = append(alerting, r)
+	}
+	return notifyData{Config: config, Results: alerting, Summary: newSummaryRecord(summarize(alerting))}
+}
+
+// notifyFuncs are the helpers available to notification templates
+var notifyFuncs = template.FuncMap{
+	// json encodes a value as a JSON literal, so strings come out quoted
+	// and escaped
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"errorText": func(err error) string {
+		if err == nil {
+			return ""
+		}
+		return err.Error()
+	},
+	"ms":           durationMs,
+	"failureLines": failureLines,
+}
+
+// failureLines lists failed and errored checks one per line
+func failureLines(results []core.Result) string {
+	var lines []string
+	for _, r := range results {
+		if r.Status != core.StatusFailed && r.Status != core.StatusErrored {
+			continue
+		}
+		line := fmt.Sprintf("%s %s %s", r.Host.CheckType, r.Host.DisplayName(), r.Status)
+		if r.Err != nil {
+			line += ": " + r.Err.Error()
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// loadNotifyTemplate parses a built-in template by name (slack, generic) or
+// a text/template file. It's rendered once against empty data so mistakes
+// like unknown fields fail at startup rather than when notifying.
+func loadNotifyTemplate(spec string) (*template.Template, error) {
+	if spec == "" {
+		spec = defaultNotifyTemplate
+	}
+	var text []byte
+	var err error
+	if builtin, berr := notifyTemplates.ReadFile("notify_templates/" + spec + ".tmpl"); berr == nil && !strings.ContainsAny(spec, `/\.`) {
+		text = builtin
+	} else if text, err = os.ReadFile(spec); err != nil {
+		return nil, fmt.Errorf("notify template %s: not a built-in (slack, generic) or readable file: %w", spec, err)
+	}
+
+	tmpl, err := template.New(spec).Funcs(notifyFuncs).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("notify template %s: %w", spec, err)
+	}
+	if err := tmpl.Execute(io.Discard, notifyData{}); err != nil {
+		return nil, fmt.Errorf("notify template %s: %w", spec, err)
+	}
+	return tmpl, nil
+}
+
+// notifyStore is the result store behind --notify-url
+type notifyStore struct {
+	url     string
+	tmpl    *template.Template
+	config  string
+	secrets []string
+	hosts   *hostRedactor
+}
+
+// Save sends one notification for the run
+func (n *notifyStore) Save(_ context.Context, results []core.Result) error {
+	return sendNotification(n.url, n.tmpl, newNotifyData(n.config, results), n.secrets, n.hosts)
+}
+
+// sendNotification renders the template and POSTs it to url. Bodies that
+// parse as JSON are sent as application/json, anything else as plain text.
+func sendNotification(url string, tmpl *template.Template, data notifyData, secrets []string, hosts *hostRedactor) error {
+	var body bytes.Buffer
+	if err := tmpl.Execute(hosts.Writer(newRedactWriter(&body, secrets)), data); err != nil {
+		return fmt.Errorf("render notification: %w", err)
+	}
+	contentType := "text/plain; charset=utf-8"
+	if json.Valid(body.Bytes()) {
+		contentType = "application/json"
+	}
+
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(url, contentType, &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify %s: unexpected status code: %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// desktopNotifyLines caps the failures listed in one desktop notification;
+// notification popups truncate long bodies anyway
+const desktopNotifyLines = 8
+
+// windowsToastScript shows a toast through the WinRT notification API. The
+// title and body come from the environment so nothing needs quoting, and
+// the toast is attributed to PowerShell, whose app ID is always registered.
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$toast = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $toast.GetElementsByTagName('text')
+$text.Item(0).AppendChild($toast.CreateTextNode($env:NETCHECK_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($toast.CreateTextNode($env:NETCHECK_NOTIFY_BODY)) > $null
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show([Windows.UI.Notifications.ToastNotification]::new($toast))`
+
+// desktopNotifyStore is the result store behind --notify-desktop: one OS
+// notification per run listing its failures, masked like the other outputs
+type desktopNotifyStore struct {
+	config  string
+	secrets []string
+	hosts   *hostRedactor
+}
+
+// Save sends one notification when the run had failures. Maintenance and
+// below --min-severity failures are left out, as for --notify-url.
+func (d *desktopNotifyStore) Save(_ context.Context, results []core.Result) error {
+	lines := failureLines(newNotifyData(d.config, results).Results)
+	if lines == "" {
+		return nil
+	}
+	body, failing := desktopNotifyBody(lines)
+	title := fmt.Sprintf("netcheck: %d check(s) failing", failing)
+	body = d.mask(body)
+
+	name, args, env := desktopNotifyCommand(title, body)
+	path, err := exec.LookPath(name)
+	if err != nil {
+		log.Warn().Err(err).Str("tool", name).Msg("desktop notifications unavailable; skipping --notify-desktop")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return fmt.Errorf("desktop notification via %s: %w", name, err)
+	}
+	return nil
+}
+
+// mask hides secrets and, with --redact, hostnames in notification text
+func (d *desktopNotifyStore) mask(s string) string {
+	var buf bytes.Buffer
+	d.hosts.Writer(newRedactWriter(&buf, d.secrets)).Write([]byte(s))
+	return buf.String()
+}
+
+// desktopNotifyBody trims failureLines output for a popup: repeated lines
+// (the same failure in every --repeat run) appear once, and past
+// desktopNotifyLines the rest are counted. It also returns the number of
+// distinct failures.
+func desktopNotifyBody(lines string) (string, int) {
+	var unique []string
+	for _, line := range strings.Split(lines, "\n") {
+		if !slices.Contains(unique, line) {
+			unique = append(unique, line)
+		}
+	}
+	count := len(unique)
+	if count > desktopNotifyLines {
+		unique = append(unique[:desktopNotifyLines], fmt.Sprintf("... and %d more", count-desktopNotifyLines))
+	}
+	return strings.Join(unique, "\n"), count
+}
+
+// desktopNotifyCommand returns the command, arguments, and extra
+// environment that show a notification: notify-send on Linux and the BSDs,
+// osascript on macOS, and a PowerShell toast on Windows
+func desktopNotifyCommand(title, body string) (string, []string, []string) {
+	switch runtime.GOOS {
+	case "darwin":
+		// Passing the text as script arguments avoids AppleScript quoting
+		return "osascript", []string{
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body,
+		}, nil
+	case "windows":
+		return "powershell.exe", []string{"-NoProfile", "-NonInteractive", "-Command", windowsToastScript},
+			[]string{"NETCHECK_NOTIFY_TITLE=" + title, "NETCHECK_NOTIFY_BODY=" + body}
+	default:
+		return "notify-send", []string{"--app-name=netcheck", "--urgency=critical", title, body}, nil
+	}
+}
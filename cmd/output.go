@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// Supported --output formats
+const (
+	outputConsole = "console"
+	outputJSON    = "json"
+	outputNDJSON  = "ndjson"
+)
+
+// resultRecord is the stable JSON shape of a single check result
+type resultRecord struct {
+	ID          string         `json:"id"`
+	Host        string         `json:"host"`
+	Label       string         `json:"label"`
+	CheckType   string         `json:"checkType"`
+	CheckLabel  string         `json:"checkLabel"`
+	Status      string         `json:"status"`
+	Error       string         `json:"error,omitempty"`
+	SkipReason  string         `json:"skipReason,omitempty"`
+	Timestamp   string         `json:"timestamp"`
+	DurationMs  float64        `json:"durationMs"`
+	Details     map[string]any `json:"details,omitempty"`
+	Maintenance bool           `json:"maintenance,omitempty"`
+	Severity    string         `json:"severity"`
+	LowSeverity bool           `json:"lowSeverity,omitempty"`
+}
+
+func newResultRecord(r core.Result) resultRecord {
+	rec := resultRecord{
+		ID:          r.Host.ID(),
+		Host:        r.Host.HostName,
+		Label:       r.Host.DisplayName(),
+		CheckType:   r.Host.CheckType,
+		CheckLabel:  checkLabelFor(r.Host.CheckType),
+		Status:      string(r.Status),
+		SkipReason:  r.SkipReason,
+		Timestamp:   r.Started.UTC().Format(time.RFC3339Nano),
+		DurationMs:  durationMs(r.Duration),
+		Details:     r.Details,
+		Maintenance: r.Maintenance,
+		Severity:    hostSeverityName(r.Host),
+		LowSeverity: r.LowSeverity,
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+	return rec
+}
+
+// summaryRecord is the JSON shape of the run summary
+type summaryRecord struct {
+	Total             int                  `json:"total"`
+	Passed            int                  `json:"passed"`
+	Failed            int                  `json:"failed"`
+	Errored           int                  `json:"errored"`
+	Unknown           int                  `json:"unknown"`
+	SkippedUnknown    int                  `json:"skippedUnknown"`
+	SkippedDependency int                  `json:"skippedDependency"`
+	SkippedNoAddress  int                  `json:"skippedNoAddress"`
+	SkippedDeadline   int                  `json:"skippedDeadline"`
+	Disabled          int                  `json:"disabled"`
+	DeadlineHosts     []string             `json:"deadlineHosts"`
+	FailedHosts       []string             `json:"failedHosts"`
+	BudgetsExhausted  int                  `json:"budgetsExhausted"`
+	Maintenance       int                  `json:"maintenance"`
+	MaintenanceHosts  []string             `json:"maintenanceHosts"`
+	LowSeverity       int                  `json:"lowSeverity"`
+	LowSeverityHosts  []string             `json:"lowSeverityHosts"`
+	DegradedHosts     []string             `json:"degradedHosts"`
+	ByTag             map[string]tagRecord `json:"byTag"`
+}
+
+// tagRecord is the JSON shape of one tag's counts in the run summary
+type tagRecord struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Errored int `json:"errored"`
+	Unknown int `json:"unknown"`
+}
+
+func newSummaryRecord(s Summary) summaryRecord {
+	failed := s.FailedHosts
+	if failed == nil {
+		failed = []string{}
+	}
+	maintenance := s.MaintenanceHosts
+	if maintenance == nil {
+		maintenance = []string{}
+	}
+	lowSeverity := s.LowSeverityHosts
+	if lowSeverity == nil {
+		lowSeverity = []string{}
+	}
+	deadline := s.DeadlineHosts
+	if deadline == nil {
+		deadline = []string{}
+	}
+	degraded := s.DegradedHosts
+	if degraded == nil {
+		degraded = []string{}
+	}
+	byTag := make(map[string]tagRecord, len(s.ByTag))
+	for tag, t := range s.ByTag {
+		byTag[tag] = tagRecord{Total: t.total(), Passed: t.Passed, Failed: t.Failed, Errored: t.Errored, Unknown: t.Unknown}
+	}
+	return summaryRecord{
+		Total:             s.Passed + s.Failed + s.Errored + s.Unknown,
+		Passed:            s.Passed,
+		Failed:            s.Failed,
+		Errored:           s.Errored,
+		Unknown:           s.Unknown,
+		SkippedUnknown:    s.SkippedUnknown,
+		SkippedDependency: s.SkippedDependency,
+		SkippedNoAddress:  s.SkippedNoAddress,
+		SkippedDeadline:   s.SkippedDeadline,
+		Disabled:          s.Disabled,
+		DeadlineHosts:     deadline,
+		FailedHosts:       failed,
+		BudgetsExhausted:  s.BudgetsExhausted,
+		Maintenance:       s.Maintenance,
+		MaintenanceHosts:  maintenance,
+		LowSeverity:       s.LowSeverity,
+		LowSeverityHosts:  lowSeverity,
+		DegradedHosts:     degraded,
+		ByTag:             byTag,
+	}
+}
+
+// validateOutput checks the --output flag value
+func validateOutput(format string) error {
+	switch format {
+	case outputConsole, outputJSON, outputNDJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q (valid: console, json, ndjson)", format)
+	}
+}
+
+// writeNDJSON writes one result as a single JSON line. The encoder writes
+// straight through to w, so each line is flushed as soon as the check ends.
+func writeNDJSON(w io.Writer, r core.Result) error {
+	return json.NewEncoder(w).Encode(newResultRecord(r))
+}
+
+// aggregateRecord is the JSON shape of a host's --repeat aggregate
+type aggregateRecord struct {
+	ID           string        `json:"id"`
+	Host         string        `json:"host"`
+	Label        string        `json:"label"`
+	CheckType    string        `json:"checkType"`
+	Runs         int           `json:"runs"`
+	Passed       int           `json:"passed"`
+	SuccessRatio float64       `json:"successRatio"`
+	MinMs        float64       `json:"minMs"`
+	MaxMs        float64       `json:"maxMs"`
+	AvgMs        float64       `json:"avgMs"`
+	Budget       *budgetRecord `json:"budget,omitempty"`
+}
+
+// budgetRecord is the JSON shape of a host's error budget
+type budgetRecord struct {
+	Allowed   int  `json:"allowed"`
+	Consumed  int  `json:"consumed"`
+	Remaining int  `json:"remaining"`
+	Exhausted bool `json:"exhausted"`
+}
+
+func newAggregateRecord(a hostAggregate) aggregateRecord {
+	rec := aggregateRecord{
+		ID:           a.Host.ID(),
+		Host:         a.Host.HostName,
+		Label:        a.Host.DisplayName(),
+		CheckType:    a.Host.CheckType,
+		Runs:         a.Runs,
+		Passed:       a.Passed,
+		SuccessRatio: a.SuccessRatio(),
+		MinMs:        durationMs(a.Min),
+		MaxMs:        durationMs(a.Max),
+		AvgMs:        durationMs(a.Avg),
+	}
+	if a.Budget >= 0 {
+		rec.Budget = &budgetRecord{
+			Allowed:   a.Budget,
+			Consumed:  a.Consumed(),
+			Remaining: a.Remaining(),
+			Exhausted: a.Exhausted(),
+		}
+	}
+	return rec
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// writeJSON writes all results and the summary (plus --repeat aggregates)
+// as one JSON document
+func writeJSON(w io.Writer, results []core.Result, summary Summary) error {
+	doc := struct {
+		Results    []resultRecord    `json:"results"`
+		Summary    summaryRecord     `json:"summary"`
+		Aggregates []aggregateRecord `json:"aggregates,omitempty"`
+	}{
+		Results: make([]resultRecord, 0, len(results)),
+		Summary: newSummaryRecord(summary),
+	}
+	for _, r := range results {
+		doc.Results = append(doc.Results, newResultRecord(r))
+	}
+	for _, a := range summary.Aggregates {
+		doc.Aggregates = append(doc.Aggregates, newAggregateRecord(a))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// errorRecord is the JSON shape of an error that stopped the run before any
+// check executed. Kind tells operator errors ("config") from outages.
+type errorRecord struct {
+	Error string `json:"error"`
+	Kind  string `json:"kind"`
+}
+
+// writeError writes a structured error object for json/ndjson output
+func writeError(w io.Writer, kind string, err error) error {
+	return json.NewEncoder(w).Encode(errorRecord{Error: err.Error(), Kind: kind})
+}
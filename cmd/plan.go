@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// Supported --print-plan formats
+const planJSON = "json"
+
+// planRecord is the JSON shape of one parsed host in --print-plan output.
+// Field names are stable so plans can be diffed across environments.
+type planRecord struct {
+	ID         string              `json:"id"`
+	Host       string              `json:"host"`
+	Label      string              `json:"label"`
+	CheckType  string              `json:"checkType"`
+	CheckLabel string              `json:"checkLabel"`
+	Known      bool                `json:"known"`
+	Timeout    string              `json:"timeout"`
+	Tokens     map[string][]string `json:"tokens"`
+	Disabled   bool                `json:"disabled,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+func newPlanRecord(host core.Host, opts *core.Options) planRecord {
+	_, known := core.CheckTypes[host.CheckType]
+	rec := planRecord{
+		ID:         host.ID(),
+		Host:       host.HostName,
+		Label:      host.DisplayName(),
+		CheckType:  host.CheckType,
+		CheckLabel: checkLabelFor(host.CheckType),
+		Known:      known,
+		Timeout:    "none",
+		Tokens:     map[string][]string(host.Tokens),
+		Disabled:   host.Disabled,
+	}
+	if rec.Tokens == nil {
+		rec.Tokens = map[string][]string{}
+	}
+	timeout, err := opts.EffectiveTimeout(host)
+	if err != nil {
+		rec.Error = err.Error()
+	} else if timeout > 0 {
+		rec.Timeout = timeout.String()
+	}
+	return rec
+}
+
+// validatePlanFormat checks the --print-plan flag value
+func validatePlanFormat(format string) error {
+	if format != "" && format != planJSON {
+		return fmt.Errorf("invalid --print-plan %q (valid: json)", format)
+	}
+	return nil
+}
+
+// writePlan writes the parsed hosts as a JSON document without running them.
+// Tokens keep their ${VAR} references so secrets stay out of the plan.
+func writePlan(w io.Writer, source string, hosts []core.Host, opts *core.Options) error {
+	doc := struct {
+		Config string       `json:"config"`
+		Hosts  []planRecord `json:"hosts"`
+	}{
+		Config: source,
+		Hosts:  make([]planRecord, 0, len(hosts)),
+	}
+	for _, host := range hosts {
+		doc.Hosts = append(doc.Hosts, newPlanRecord(host, opts))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
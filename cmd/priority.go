@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// hostPriority returns a host's priority= token, 0 when unset. Higher
+// priorities run first.
+func hostPriority(host core.Host) (int, error) {
+	if !host.Tokens.Has("priority") {
+		return 0, nil
+	}
+	value := host.Tokens.Get("priority")
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("host %q: invalid priority %q: must be an integer", host.DisplayName(), value)
+	}
+	return priority, nil
+}
+
+// orderByPriority stable-sorts hosts by descending priority, so hosts
+// without a priority= token keep config order. Dependency ordering runs
+// afterwards and pulls prerequisites ahead of the hosts needing them.
+func orderByPriority(hosts []core.Host) ([]core.Host, error) {
+	priorities := make([]int, len(hosts))
+	order := make([]int, len(hosts))
+	for i, host := range hosts {
+		priority, err := hostPriority(host)
+		if err != nil {
+			return nil, err
+		}
+		priorities[i] = priority
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return priorities[b] - priorities[a]
+	})
+	sorted := make([]core.Host, len(hosts))
+	for i, idx := range order {
+		sorted[i] = hosts[idx]
+	}
+	return sorted, nil
+}
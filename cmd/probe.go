@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// --probe-quorum policies deciding how many checks must pass
+const (
+	probePolicyAll    = "all"
+	probePolicyAny    = "any"
+	probePolicyQuorum = "quorum"
+)
+
+// validateProbeQuorum checks the --probe-quorum value before the config is
+// known (a count is only bounded by the host total later)
+func validateProbeQuorum(policy string) error {
+	switch strings.ToLower(policy) {
+	case probePolicyAll, probePolicyAny, probePolicyQuorum:
+		return nil
+	}
+	if n, err := strconv.Atoi(policy); err != nil || n < 1 {
+		return fmt.Errorf("invalid --probe-quorum %q: want all, any, quorum, or a count of at least 1", policy)
+	}
+	return nil
+}
+
+// probeRequired is the number of checks out of total that must pass
+func probeRequired(policy string, total int) int {
+	switch strings.ToLower(policy) {
+	case probePolicyAll:
+		return total
+	case probePolicyAny:
+		return 1
+	case probePolicyQuorum:
+		return total/2 + 1
+	}
+	n, _ := strconv.Atoi(policy)
+	return n
+}
+
+// probeVerdict decides a --probe run. Hosts skipped for an unknown type or
+// disabled in the config don't count; hosts skipped behind a failed
+// dependency count as not passing, and failures below --min-severity as
+// passing. A config with nothing to check is never healthy.
+func probeVerdict(results []core.Result, summary Summary, policy string) error {
+	total := 0
+	for _, r := range results {
+		if r.Status != core.StatusSkipped || (r.SkipReason != skipUnknownType && r.SkipReason != skipDisabled) {
+			total++
+		}
+	}
+	if total == 0 {
+		return &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("probe: no checks ran")}
+	}
+
+	required := probeRequired(policy, total)
+	if summary.Passed+summary.LowSeverity >= required {
+		return nil
+	}
+	reason := fmt.Sprintf("probe: %d/%d checks passed, need %d", summary.Passed, total, required)
+	if len(summary.FailedHosts) > 0 {
+		reason += " (failed: " + strings.Join(summary.FailedHosts, ", ") + ")"
+	}
+	return &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("%s", reason)}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// waitForKey shows the exit prompt and returns on a single keypress. When
+// stdin isn't a terminal (piped, redirected, or closed) nobody can answer,
+// so the prompt is skipped as if --batch were set.
+func waitForKey(out io.Writer) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return
+	}
+	fmt.Fprint(out, "Press any key to exit...")
+
+	// Raw mode delivers the key without waiting for Enter; fall back to a
+	// line read on terminals that refuse it
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		var input string
+		fmt.Scanln(&input)
+		return
+	}
+	key := make([]byte, 1)
+	os.Stdin.Read(key)
+	term.Restore(fd, state)
+	fmt.Fprintln(out)
+}
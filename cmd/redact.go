@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// redactAliasLength is how many hex characters of the keyed hash name a
+// redacted host
+const redactAliasLength = 8
+
+// reHostWord matches a run of hostname characters in output; each run is
+// looked up whole, so "db" never masks part of "dbus". Console color codes
+// are matched first so "\x1b[0m" doesn't glue its "0m" onto the next name.
+var reHostWord = regexp.MustCompile(`\x1b\[[0-9;]*m|[A-Za-z0-9_][A-Za-z0-9_.-]*`)
+
+// hostRedactor masks configured hostnames for --redact. Each name becomes
+// host-<hash>, keyed with a random per-run salt: the same host reads the same
+// throughout one run, but aliases can't be matched across runs or guessed
+// from a list of likely names. A nil hostRedactor masks nothing.
+type hostRedactor struct {
+	salt []byte
+
+	mu      sync.RWMutex
+	aliases map[string]string // lowercased name -> alias
+}
+
+func newHostRedactor() (*hostRedactor, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &hostRedactor{salt: salt, aliases: map[string]string{}}, nil
+}
+
+// AddHosts registers the names the hosts connect to. Output written before
+// the config is parsed (e.g. config fetch errors) isn't masked.
+func (h *hostRedactor) AddHosts(hosts []core.Host) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, host := range hosts {
+		for _, name := range hostNames(host) {
+			key := strings.ToLower(name)
+			if _, ok := h.aliases[key]; ok {
+				continue
+			}
+			mac := hmac.New(sha256.New, h.salt)
+			mac.Write([]byte(key))
+			h.aliases[key] = "host-" + hex.EncodeToString(mac.Sum(nil))[:redactAliasLength]
+		}
+	}
+}
+
+// Mapping returns the registered names and their aliases
+func (h *hostRedactor) Mapping() map[string]string {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	mapping := make(map[string]string, len(h.aliases))
+	for name, alias := range h.aliases {
+		mapping[name] = alias
+	}
+	return mapping
+}
+
+// Replace masks every registered hostname in s. A trailing dot (end of a
+// sentence, or a fully-qualified name) stays outside the match.
+func (h *hostRedactor) Replace(s string) string {
+	if h == nil {
+		return s
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.aliases) == 0 {
+		return s
+	}
+	return reHostWord.ReplaceAllStringFunc(s, func(word string) string {
+		if word[0] == '\x1b' {
+			return word
+		}
+		name := strings.TrimRight(word, ".")
+		if alias, ok := h.aliases[strings.ToLower(name)]; ok {
+			return alias + word[len(name):]
+		}
+		return word
+	})
+}
+
+// Writer wraps w so everything written through it is masked
+func (h *hostRedactor) Writer(w io.Writer) io.Writer {
+	if h == nil {
+		return w
+	}
+	return &hostRedactWriter{w: w, redactor: h}
+}
+
+type hostRedactWriter struct {
+	w        io.Writer
+	redactor *hostRedactor
+}
+
+func (r *hostRedactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, r.redactor.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	// Report the original length so callers don't treat redaction as a short write
+	return len(p), nil
+}
+
+// hostNames returns the names a host connects to: the host part of its
+// hostname or of each URL in it. Script checks skip the script name, SVC
+// checks name a local service rather than a host, and MULT checks skip the
+// policy and weight= fields. ${VAR} references are
+// resolved so names held in secrets are masked too.
+func hostNames(host core.Host) []string {
+	fields := strings.Fields(host.Expanded().HostName)
+	switch host.CheckType {
+	case "LUA", "PY", "PS":
+		if len(fields) > 0 {
+			fields = fields[1:]
+		}
+	case "SVC":
+		return nil
+	}
+	var names []string
+	for _, field := range fields {
+		var name string
+		switch {
+		case strings.Contains(field, "://"):
+			u, err := url.Parse(field)
+			if err != nil {
+				continue
+			}
+			name = u.Hostname()
+		case host.CheckType == "MULT" || strings.Contains(field, "="):
+			continue
+		default:
+			name = field
+			if h, _, err := net.SplitHostPort(field); err == nil {
+				name = h
+			}
+			name = strings.Trim(name, "[]")
+		}
+		if name = strings.TrimRight(name, "."); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// logRedactMapping records each alias in the transcript alone, so a vendor's
+// question about host-1a2b3c4d can be traced back to the real host
+func logRedactMapping(transcript io.Writer, h *hostRedactor) {
+	logger := zerolog.New(transcript).With().Timestamp().Logger()
+	mapping := h.Mapping()
+	names := make([]string, 0, len(mapping))
+	for name := range mapping {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		logger.Info().Str("host", name).Str("alias", mapping[name]).Msg("redacted host")
+	}
+}
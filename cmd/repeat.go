@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// hostAggregate summarizes one host's results across --repeat runs
+type hostAggregate struct {
+	Host   core.Host
+	Runs   int
+	Passed int
+	Min    time.Duration
+	Max    time.Duration
+	Avg    time.Duration
+
+	// Budget is the number of failed or slow checks the host's budget=
+	// allows; -1 when the host has no budget
+	Budget int
+}
+
+// Consumed is the number of runs that counted against the error budget
+func (a hostAggregate) Consumed() int {
+	return a.Runs - a.Passed
+}
+
+// Remaining is the budget left after this run (negative once overspent)
+func (a hostAggregate) Remaining() int {
+	return a.Budget - a.Consumed()
+}
+
+// Exhausted reports whether the host used more than its error budget
+func (a hostAggregate) Exhausted() bool {
+	return a.Budget >= 0 && a.Remaining() < 0
+}
+
+// hasBudgets reports whether any host sets budget=
+func hasBudgets(hosts []core.Host) bool {
+	for _, host := range hosts {
+		if host.Tokens.Has("budget") {
+			return true
+		}
+	}
+	return false
+}
+
+// unbudgetedHosts drops the names of hosts with a budget= from names
+func unbudgetedHosts(names []string, hosts []core.Host) []string {
+	budgeted := map[string]bool{}
+	for _, host := range hosts {
+		if host.Tokens.Has("budget") {
+			budgeted[host.DisplayName()] = true
+		}
+	}
+	var kept []string
+	for _, name := range names {
+		if !budgeted[name] {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// SuccessRatio is the fraction of runs in which the host passed
+func (a hostAggregate) SuccessRatio() float64 {
+	if a.Runs == 0 {
+		return 0
+	}
+	return float64(a.Passed) / float64(a.Runs)
+}
+
+// aggregateRuns folds the per-run results (same host order in every run)
+// into one aggregate per executed host. Unknown and skipped hosts are left out.
+func aggregateRuns(runs [][]core.Result) []hostAggregate {
+	if len(runs) == 0 {
+		return nil
+	}
+
+	var aggregates []hostAggregate
+	for i := range runs[0] {
+		host := runs[0][i].Host
+		agg := hostAggregate{Host: host, Budget: -1}
+		if budget, err := host.Budget(); err != nil {
+			log.Warn().Err(err).Str("host", host.HostName).Msg("ignoring error budget")
+		} else {
+			agg.Budget = budget
+		}
+		var total time.Duration
+		for _, run := range runs {
+			r := run[i]
+			if r.Status == core.StatusUnknown || r.Status == core.StatusSkipped {
+				continue
+			}
+			agg.Runs++
+			if r.Passed() {
+				agg.Passed++
+			}
+			if agg.Runs == 1 || r.Duration < agg.Min {
+				agg.Min = r.Duration
+			}
+			if r.Duration > agg.Max {
+				agg.Max = r.Duration
+			}
+			total += r.Duration
+		}
+		if agg.Runs == 0 {
+			continue
+		}
+		agg.Avg = total / time.Duration(agg.Runs)
+		aggregates = append(aggregates, agg)
+	}
+	return aggregates
+}
+
+// logAggregates writes one stability line per host and returns how many
+// hosts fell below the minimum success ratio and how many exhausted their
+// error budget. Hosts below --min-severity are logged as warnings and not
+// counted.
+func logAggregates(aggregates []hostAggregate, minRatio float64) (below, exhausted int) {
+	for _, agg := range aggregates {
+		event := log.Info()
+		unstable, spent := agg.SuccessRatio() < minRatio, agg.Exhausted()
+		switch {
+		case (unstable || spent) && belowMinSeverity(agg.Host):
+			event = log.Warn()
+		case unstable || spent:
+			event = log.Error()
+			if unstable {
+				below++
+			}
+			if spent {
+				exhausted++
+			}
+		}
+		if agg.Budget >= 0 {
+			event = event.Int("budget", agg.Budget).Int("budgetConsumed", agg.Consumed()).Int("budgetRemaining", agg.Remaining())
+		}
+		event.Str("host", agg.Host.HostName).
+			Str("label", agg.Host.DisplayName()).
+			Str("checkType", agg.Host.CheckType).
+			Int("passed", agg.Passed).
+			Int("runs", agg.Runs).
+			Float64("successRatio", agg.SuccessRatio()).
+			Dur("min", agg.Min).
+			Dur("max", agg.Max).
+			Dur("avg", agg.Avg).
+			Msgf("host passed %d/%d runs", agg.Passed, agg.Runs)
+	}
+	return below, exhausted
+}
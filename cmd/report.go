@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// outputJUnit writes a JUnit XML report (one test case per check)
+const outputJUnit = "junit"
+
+// outputTarget is one entry of --output: a format and an optional file
+// ("" writes to stdout)
+type outputTarget struct {
+	Format string
+	Path   string
+}
+
+// parseOutputs parses "console,json:results.json,html:report.html". Formats
+// are looked up in the formatter registry. Only one structured format may
+// use stdout, and each file may be written once.
+func parseOutputs(spec string) ([]outputTarget, error) {
+	var targets []outputTarget
+	stdoutFormat := ""
+	paths := map[string]bool{}
+	for _, entry := range strings.Split(spec, ",") {
+		format, path, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		format = strings.ToLower(format)
+		if _, ok := lookupFormatter(format); !ok {
+			return nil, fmt.Errorf("unknown output format %q (valid: %s)", format, strings.Join(formatterNames(), ", "))
+		}
+
+		switch {
+		case format == outputConsole && path != "":
+			return nil, fmt.Errorf("console output can't be written to a file")
+		case format == outputConsole:
+		case path == "":
+			if stdoutFormat != "" {
+				return nil, fmt.Errorf("both %s and %s write to stdout; give one a file (e.g. %s:results.%s)", stdoutFormat, format, format, format)
+			}
+			stdoutFormat = format
+		case paths[path]:
+			return nil, fmt.Errorf("output file %s is used more than once", path)
+		default:
+			paths[path] = true
+		}
+		targets = append(targets, outputTarget{Format: format, Path: path})
+	}
+	return targets, nil
+}
+
+// reporter dispatches results to every --output target's formatter
+type reporter struct {
+	targets    []outputTarget
+	formatters []Formatter
+	writers    []io.Writer
+	files      []*os.File
+}
+
+// openReports creates the output files; targets without a file use stdout
+func openReports(targets []outputTarget, stdout io.Writer, secrets []string, hosts *hostRedactor) (*reporter, error) {
+	r := &reporter{targets: targets}
+	for _, t := range targets {
+		f, ok := lookupFormatter(t.Format)
+		if !ok {
+			r.Close()
+			return nil, fmt.Errorf("unknown output format %q", t.Format)
+		}
+		r.formatters = append(r.formatters, f)
+		if t.Path == "" {
+			r.writers = append(r.writers, stdout)
+			continue
+		}
+		file, err := os.Create(t.Path)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("open %s output: %w", t.Format, err)
+		}
+		r.files = append(r.files, file)
+		r.writers = append(r.writers, hosts.Writer(newRedactWriter(file, secrets)))
+	}
+	return r, nil
+}
+
+// StructuredStdout reports whether a structured format writes to stdout
+func (r *reporter) StructuredStdout() bool {
+	for _, t := range r.targets {
+		if t.Format != outputConsole && t.Path == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Result streams one result to the streaming targets (ndjson) as soon as
+// it completes
+func (r *reporter) Result(result core.Result) {
+	for i, t := range r.targets {
+		streamer, ok := r.formatters[i].(ResultStreamer)
+		if !ok {
+			continue
+		}
+		if err := streamer.FormatResult(r.writers[i], result); err != nil {
+			log.Error().Err(err).Str("output", t.Format).Msg("failed to write result")
+		}
+	}
+}
+
+// Finish runs every target's formatter once every check is done
+func (r *reporter) Finish(results []core.Result, summary Summary) error {
+	for i, t := range r.targets {
+		if err := r.formatters[i].Format(r.writers[i], results, summary); err != nil {
+			return fmt.Errorf("write %s output: %w", t.Format, err)
+		}
+	}
+	return nil
+}
+
+// Error writes a structured error to the targets that can report one (json,
+// ndjson) in place of results
+func (r *reporter) Error(kind string, err error) {
+	for i, t := range r.targets {
+		errFormatter, ok := r.formatters[i].(ErrorFormatter)
+		if !ok {
+			continue
+		}
+		if werr := errFormatter.FormatError(r.writers[i], kind, err); werr != nil {
+			log.Error().Err(werr).Str("output", t.Format).Msg("failed to write error")
+		}
+	}
+}
+
+// Close closes the output files
+func (r *reporter) Close() {
+	for _, file := range r.files {
+		file.Close()
+	}
+}
+
+// JUnit XML shapes, following the common Jenkins/GitLab schema
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnit writes the results as a JUnit XML report: one test case per
+// check, classed by check type
+func writeJUnit(w io.Writer, results []core.Result) error {
+	suite := junitSuite{Name: "netcheck", Tests: len(results)}
+	var total float64
+	for _, r := range results {
+		seconds := r.Duration.Seconds()
+		total += seconds
+		tc := junitCase{
+			Name:      r.Host.DisplayName(),
+			ClassName: r.Host.CheckType,
+			Time:      fmt.Sprintf("%.3f", seconds),
+		}
+		message := ""
+		if r.Err != nil {
+			message = r.Err.Error()
+		}
+		switch r.Status {
+		case core.StatusFailed:
+			if message == "" {
+				message = "check failed"
+			}
+			tc.Failure = &junitMessage{Message: message}
+			suite.Failures++
+		case core.StatusErrored, core.StatusUnknown:
+			tc.Error = &junitMessage{Message: message}
+			suite.Errors++
+		case core.StatusSkipped:
+			tc.Skipped = &junitMessage{Message: r.SkipReason}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// outputCSV writes a CSV table (one row per check) for spreadsheets
+const outputCSV = "csv"
+
+// csvHeader names the CSV columns, matching the JSON result fields
+var csvHeader = []string{"id", "host", "label", "checkType", "status", "error", "skipReason", "timestamp", "durationMs", "severity", "maintenance"}
+
+// writeCSV writes the results as CSV with a header row. Details are left
+// out; they have no fixed columns.
+func writeCSV(w io.Writer, results []core.Result, _ Summary) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		rec := newResultRecord(r)
+		row := []string{
+			rec.ID, rec.Host, rec.Label, rec.CheckType, rec.Status, rec.Error, rec.SkipReason,
+			rec.Timestamp, strconv.FormatFloat(rec.DurationMs, 'f', -1, 64), rec.Severity, strconv.FormatBool(rec.Maintenance),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// outputHTML writes a standalone HTML report (one table row per check)
+const outputHTML = "html"
+
+//go:embed report_templates/html.tmpl
+var reportTemplates embed.FS
+
+// htmlReport is parsed once from the embedded template; the page carries
+// its CSS inline so it can be mailed as a single file
+var htmlReport = template.Must(template.ParseFS(reportTemplates, "report_templates/html.tmpl"))
+
+// htmlReportData is what the HTML template renders
+type htmlReportData struct {
+	Config    string
+	Generated string
+	Summary   summaryRecord
+	Skipped   int
+	Rows      []htmlRow
+}
+
+// htmlRow is one check in the HTML report
+type htmlRow struct {
+	Label, Host, Type string
+	Status, Class     string
+	Started, Duration string
+	// Message is the error or skip reason; Details the check's recorded
+	// details as indented JSON. Both show in an expandable cell.
+	Message, Details string
+}
+
+// writeHTML renders the results as a self-contained HTML page with a
+// pass/fail banner above the results table
+func writeHTML(w io.Writer, config string, results []core.Result, summary Summary) error {
+	data := htmlReportData{
+		Config:    config,
+		Generated: time.Now().Format(time.RFC1123),
+		Summary:   newSummaryRecord(summary),
+		Rows:      make([]htmlRow, 0, len(results)),
+	}
+	for _, r := range results {
+		row := htmlRow{
+			Label:    r.Host.DisplayName(),
+			Host:     r.Host.HostName,
+			Type:     r.Host.CheckType,
+			Status:   string(r.Status),
+			Class:    string(r.Status),
+			Started:  r.Started.Format("2006-01-02 15:04:05"),
+			Duration: fmt.Sprintf("%.1f ms", durationMs(r.Duration)),
+		}
+		switch {
+		case r.Err != nil:
+			row.Message = r.Err.Error()
+		case r.Status == core.StatusSkipped:
+			row.Message = r.SkipReason
+			data.Skipped++
+		}
+		if r.Maintenance && !r.Passed() {
+			row.Class = "maintenance"
+			row.Status += " (maintenance)"
+		}
+		if len(r.Details) > 0 {
+			details, err := json.MarshalIndent(r.Details, "", "  ")
+			if err != nil {
+				return err
+			}
+			row.Details = string(details)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	return htmlReport.Execute(w, data)
+}
@@ -1,16 +1,22 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/time/rate"
 	"nexus-sds.com/netcheck/pkg/core"
 )
 
@@ -18,10 +24,125 @@ var (
 	cfgFile        string
 	batchMode      bool
 	transcriptPath string
+	retries        int
+	retryDelay     time.Duration
+	retryOnSpec    string
+	configFormat   string
+	ignoreUnknown  bool
+	secretsFile    string
+	combFast       bool
+	dualStack      bool
+	combSticky     bool
+	checkTimeout   time.Duration
+	outputFormat   string
+	aliasSpecs     []string
+	portSpecs      []string
+	caBundle       string
+	caAppend       bool
+	repeatCount    int
+	socks5Proxy    string
+	tuiMode        bool
+	printPlan      string
+	requireHosts   bool
+	checkRate      float64
+	minSuccess     float64
+	probeMode      bool
+	probeQuorum    string
+	notifyURL      string
+	notifyTmpl     string
+	notifyDesktop  bool
+	maintSpecs     []string
+	minSeverityArg string
+	onChange       string
+	onChangeWait   time.Duration
+	preHookCmd     string
+	gatewayCheck   bool
+	gatewayAddr    string
+	postHookCmd    string
+	hookTimeout    time.Duration
+	maxRuntime     time.Duration
+	maxProcs       int
+	failuresOnly   bool
+	configDir      string
+	countOnly      bool
+	metricsFile    string
+	summaryJSON    string
+	strictTokens   bool
+	waitHealthy    bool
+	waitTimeout    time.Duration
+	waitInterval   time.Duration
+	commentFlag    string
+	fieldSepFlag   string
+	pingBin        string
+	icmpMode       string
+	pythonBin      string
+	pwshBin        string
+	sshBin         string
+	keepAlive      time.Duration
+	dnsTTL         time.Duration
+	idleTimeout    time.Duration
+	maxBodySize    string
+	baselineDir    string
+	dataFile       string
+	updateBaseline bool
+	redactHosts    bool
+	keepHostsLog   bool
+	syslogAddr     string
+	syslogProto    string
+	syslogFacility string
+	syslogTag      string
+	sortBy         string
+	failedConfig   string
 )
 
-// Precompiled regex for config lines: 2-4 char check type + whitespace + hostname
-var reLine = regexp.MustCompile(`^([a-zA-Z0-9]{2,4})\s+(.+)$`)
+// Skip reasons reported in results
+const (
+	skipUnknownType = "unknown check type"
+	skipDependency  = "dependency failed"
+	skipDeadline    = "run deadline exceeded"
+	skipDisabled    = "disabled"
+	skipNoAddress   = "no address in family"
+)
+
+// Summary tallies check outcomes for the end-of-run summary. Formatters get
+// it alongside the results.
+type Summary struct {
+	Passed         int
+	Failed         int
+	Errored        int
+	Unknown        int
+	SkippedUnknown int
+	// SkippedDependency counts hosts not run because a depends= host failed
+	SkippedDependency int
+	// SkippedDeadline counts hosts not started before --max-runtime ran
+	// out; they're listed in DeadlineHosts
+	SkippedDeadline int
+	DeadlineHosts   []string
+	// SkippedNoAddress counts dual-stack halves skipped because the host
+	// has no address in that family
+	SkippedNoAddress int
+	// DegradedHosts are the dual-stack hosts that passed over one address
+	// family and failed over the other
+	DegradedHosts []string
+	// Disabled counts hosts switched off in the config ("!" or disabled)
+	Disabled    int
+	FailedHosts []string
+	// Maintenance counts failures inside a maintenance window; those hosts
+	// are listed in MaintenanceHosts instead of FailedHosts
+	Maintenance      int
+	MaintenanceHosts []string
+	// LowSeverity counts failures of hosts below --min-severity; those
+	// hosts are listed in LowSeverityHosts instead of FailedHosts
+	LowSeverity      int
+	LowSeverityHosts []string
+	BudgetsExhausted int
+	// ByTag tallies results per tag= tag; a host with several tags counts
+	// under each
+	ByTag map[string]tagTally
+	// Aggregates are the per-host results across --repeat runs (or for
+	// budget= hosts), empty otherwise
+	Aggregates []hostAggregate
+}
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -32,8 +153,11 @@ health checks on hosts using various check types including ICMP ping, HTTP,
 HTTPS, combo checks, and custom scripts (Lua, Python, PowerShell).
 
 The tool reads a simple config file format and executes network checks based
-on the configuration.`,
-	RunE: runNetcheck,
+on the configuration. It exits 0 when every check passes, 1 when any fails
+(outside a maintenance window and at or above --min-severity), and 2 on a
+config error.`,
+	PersistentPreRunE: applyGlobalFlags,
+	RunE:              runNetcheck,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -43,62 +167,637 @@ func Execute() error {
 
 func init() {
 	// Define flags
-	rootCmd.Flags().StringVarP(&cfgFile, "config", "f", "netcheck.txt", "path to config file")
+	rootCmd.PersistentFlags().StringSliceVar(&aliasSpecs, "alias", nil, "check type alias NAME=CODE (repeatable, e.g. --alias PNG=ICMP)")
+	rootCmd.PersistentFlags().StringSliceVar(&portSpecs, "default-port", nil, "override a check type's default port TYPE=N (repeatable, e.g. --default-port HTTP=8080)")
+	rootCmd.Flags().StringVarP(&cfgFile, "config", "f", "netcheck.txt", "path or http(s) URL of the config file ('-' for stdin)")
+	rootCmd.Flags().StringVar(&configDir, "config-dir", "", "load every *.txt, *.yaml, *.yml, and *.json config in this directory, in filename order")
+	rootCmd.MarkFlagsMutuallyExclusive("config", "config-dir")
+	rootCmd.Flags().StringVar(&commentFlag, "comment-char", "#", "character starting comment lines (and #name: labels) in text configs")
+	rootCmd.Flags().StringVar(&fieldSepFlag, "field-sep", "", "field separator for text configs, e.g. ';' or tab (default: whitespace)")
+	rootCmd.Flags().BoolVar(&strictTokens, "strict-tokens", false, "fail (exit 2) on per-host tokens the check type doesn't use, instead of warning")
+	rootCmd.Flags().StringVar(&configFormat, "config-format", "", "force config format: text, yaml, json (default: detect from extension)")
 	rootCmd.Flags().BoolVarP(&batchMode, "batch", "b", false, "batch mode - disable 'press any key' prompt")
 	rootCmd.Flags().StringVarP(&transcriptPath, "log", "l", "", "path to transcript log file")
+	rootCmd.Flags().BoolVar(&keepHostsLog, "unredacted-transcript", false, "with --redact, keep real hostnames and the alias mapping in the --log transcript")
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", outputConsole, "comma-separated outputs, each FORMAT[:file]: console, json (batched), ndjson (streamed), junit, html, csv (stdout when no file)")
+	rootCmd.Flags().StringVar(&sortBy, "sort", "", "order results in json, junit, and html output and the summary host lists: status, latency, host, or type (default: execution order)")
+	rootCmd.Flags().StringVar(&printPlan, "print-plan", "", "print the parsed check plan in this format (json) and exit without running checks")
+	rootCmd.Flags().BoolVar(&requireHosts, "require-hosts", false, "fail (exit 2) when the config has no runnable hosts")
+	rootCmd.Flags().BoolVar(&ignoreUnknown, "ignore-unknown", false, "quietly skip hosts with unknown check types")
+	rootCmd.Flags().BoolVar(&countOnly, "count-only", false, "print only one summary line (e.g. for cron mail subjects) to stdout; per-host logs go to the transcript only")
+	rootCmd.Flags().BoolVar(&failuresOnly, "failures-only", false, "only log failed and errored checks; passes still count in the summary and structured output")
+	rootCmd.Flags().BoolVar(&tuiMode, "tui", false, "show a live host status table instead of scrolling logs (needs a terminal)")
+	rootCmd.Flags().IntVar(&repeatCount, "repeat", 1, "run the whole config N times back-to-back and report per-host stability")
+	rootCmd.Flags().BoolVar(&waitHealthy, "wait-for-healthy", false, "re-run the whole config until every check passes (exit 0) or --wait-timeout elapses (exit 1)")
+	rootCmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 5*time.Minute, "how long --wait-for-healthy keeps trying")
+	rootCmd.Flags().DurationVar(&waitInterval, "wait-interval", 10*time.Second, "pause between --wait-for-healthy attempts")
+	rootCmd.Flags().DurationVar(&maxRuntime, "max-runtime", 0, "stop starting checks after this long and report the rest as skipped; higher priority= hosts run first (0 = no limit)")
+	rootCmd.Flags().Float64Var(&minSuccess, "min-success-ratio", 1.0, "with --repeat, fail when any host's success ratio is below this (0-1)")
+	rootCmd.Flags().BoolVar(&probeMode, "probe", false, "health probe mode (e.g. Kubernetes exec probes): one run, no prompt or logs, exit 0 only when --probe-quorum checks pass")
+	rootCmd.Flags().StringVar(&probeQuorum, "probe-quorum", probePolicyAll, "checks that must pass in --probe mode: all, any, quorum, or a count")
+	rootCmd.Flags().StringArrayVar(&maintSpecs, "maintenance", nil, "maintenance window '[DAYS] HH:MM-HH:MM [TZ]' (repeatable, e.g. \"Sat 02:00-04:00 UTC\"); failures inside it are warnings and don't notify")
+	rootCmd.Flags().StringVar(&minSeverityArg, "min-severity", "info", "only failures of hosts at or above this severity= (critical, warning, info) alert and affect the exit code; the rest are logged as warnings")
+	rootCmd.Flags().StringVar(&onChange, "on-change", "", "command run when a host goes up or down between --repeat runs; {host} {label} {type} {state} {prev} {error} are substituted")
+	rootCmd.Flags().DurationVar(&onChangeWait, "on-change-timeout", 30*time.Second, "time limit for each --on-change command")
+	rootCmd.Flags().StringVar(&preHookCmd, "pre-hook", "", "command run before checks start; if it fails the run is aborted")
+	rootCmd.Flags().StringVar(&postHookCmd, "post-hook", "", "command run after the run, whatever its results; counts are passed as NETCHECK_* environment variables")
+	rootCmd.Flags().BoolVar(&combSticky, "comb-sticky", false, "with --repeat or --wait-for-healthy, COMB checks remember the scheme that answered and probe only it until it fails")
+	rootCmd.Flags().BoolVar(&gatewayCheck, "gateway-check", false, "ping the default gateway before checking hosts and abort with \"local network down\" if it doesn't answer")
+	rootCmd.Flags().StringVar(&gatewayAddr, "gateway", "", "gateway address for --gateway-check instead of the detected default gateway (implies --gateway-check)")
+	rootCmd.Flags().DurationVar(&hookTimeout, "hook-timeout", time.Minute, "time limit for each --pre-hook and --post-hook command")
+	rootCmd.Flags().StringVar(&summaryJSON, "summary-json", "", "write a run-level summary (counts, start/end time, duration) to this JSON file, even when the run fails")
+	rootCmd.Flags().StringVar(&failedConfig, "failed-config", "", "after the run, write the config lines of hosts that failed or errored to this file, for re-running with -f")
+	rootCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "write Prometheus text metrics to this file after each run, atomically (for node_exporter's textfile collector)")
+	rootCmd.Flags().StringVar(&syslogAddr, "syslog", "", "send one message per check result to this syslog server (host:port); failures are err, passes info")
+	rootCmd.Flags().StringVar(&syslogProto, "syslog-proto", "udp", "syslog transport: udp or tcp")
+	rootCmd.Flags().StringVar(&syslogFacility, "syslog-facility", "daemon", "syslog facility, e.g. daemon, user, or local0-local7")
+	rootCmd.Flags().StringVar(&syslogTag, "syslog-tag", "netcheck", "syslog tag (program name) on each message")
+	rootCmd.Flags().StringVar(&notifyURL, "notify-url", "", "POST a notification rendered from --notify-template to this URL after the run")
+	rootCmd.Flags().StringVar(&notifyTmpl, "notify-template", "", "notification body: built-in slack or generic, or a text/template file (default generic)")
+	rootCmd.Flags().BoolVar(&dualStack, "dual-stack", false, "check every host over IPv4 and IPv6 separately, reporting \"host (v4)\" and \"host (v6)\" (like ipv=both on each host)")
+	rootCmd.Flags().BoolVar(&notifyDesktop, "notify-desktop", false, "after a run with failures, show one desktop notification listing them (notify-send, osascript, or a Windows toast)")
+	addCheckFlags(rootCmd.Flags())
+
+	core.RegisterResultStore("metrics", metricsStore{})
 }
 
-func parseHostString(input string) (*core.Host, error) {
-	input = strings.TrimSpace(input)
-	matches := reLine.FindStringSubmatch(input)
+// addCheckFlags registers the flags that tune how checks run. They're shared
+// by the root command and `netcheck run`.
+func addCheckFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&secretsFile, "secrets-file", "", "dotenv-style file of secrets loaded into the environment for ${VAR} expansion")
+	flags.BoolVar(&redactHosts, "redact", false, "mask hostnames in console, structured, and summary output as host-<hash>, stable within the run")
+	flags.BoolVar(&combFast, "comb-fast", false, "COMB checks send HEAD requests to both schemes at once and stop at the first success")
+	flags.StringVar(&caBundle, "ca-bundle", "", "PEM CA bundle used to verify HTTPS servers (per-host cacert= overrides)")
+	flags.BoolVar(&caAppend, "ca-append", false, "append CA bundles to the system roots instead of replacing them")
+	flags.DurationVar(&checkTimeout, "timeout", 0, "default timeout for every check unless the host sets timeout= (default: per check type)")
+	flags.DurationVar(&keepAlive, "keepalive", 0, "TCP keep-alive period for check connections, e.g. 10s to outlast short NAT timeouts; negative disables (default: Go's 15s)")
+	flags.DurationVar(&dnsTTL, "dns-ttl", 0, "reuse a resolved address across checks for this long before looking the name up again, e.g. 30s (0 = every check resolves on its own)")
+	flags.DurationVar(&idleTimeout, "idle-timeout", 0, "how long an HTTP check keeps an idle connection for reuse within the check (redirects, COMB/MULT requests) (default 90s)")
+	flags.StringVar(&maxBodySize, "max-body-size", "10MB", "most of a response body an HTTP check reads; body assertions fail on longer bodies (per-host maxbody= overrides)")
+	flags.StringVar(&socks5Proxy, "socks5", "", "route TCP-based checks through a SOCKS5 proxy at [user:pass@]host:port")
+	flags.Float64Var(&checkRate, "rate", 0, "maximum checks started per second across the run, retries included (0 = unlimited)")
+	flags.StringVar(&pingBin, "ping-bin", "", "ping binary for ICMP checks (env "+envPingBin+"; default: ping on PATH)")
+	flags.StringVar(&icmpMode, "icmp-mode", core.ICMPModeAuto, "how ICMP checks ping: auto (datagram socket, then raw socket, then ping), dgram, raw, or exec (ping command)")
+	flags.StringVar(&pythonBin, "python-bin", "", "Python interpreter for PY checks (env "+envPythonBin+"; default: python3 or python on PATH)")
+	flags.StringVar(&pwshBin, "pwsh-bin", "", "PowerShell binary for PS checks (env "+envPwshBin+"; default: pwsh or powershell on PATH)")
+	flags.StringVar(&sshBin, "ssh-bin", "", "ssh client for via= checks (env "+envSSHBin+"; default: ssh on PATH)")
+	flags.IntVar(&maxProcs, "max-procs", 0, "maximum external processes (ping, python, pwsh) checks run at once, separate from network concurrency (0 = unlimited)")
+	flags.StringVar(&dataFile, "data-file", "", "JSON file of expected values handed to LUA (data global) and PY/PS ("+core.EnvData+", "+core.EnvDataFile+") checks")
+	flags.StringVar(&baselineDir, "baseline-dir", "", "directory of stored response bodies that diff= HTTP/HTPS checks compare against")
+	flags.BoolVar(&updateBaseline, "update-baseline", false, "replace the stored baselines with the current responses")
+	flags.IntVar(&retries, "retries", 0, "number of times to retry a failed check")
+	flags.DurationVar(&retryDelay, "retry-delay", time.Second, "delay between retries of a failed check")
+	flags.StringVar(&retryOnSpec, "retry-on", "timeout,5xx", "error classes to retry: timeout, 5xx, connrefused, all")
+}
+
+// checkLimiter gates every check attempt when --rate is set
+var checkLimiter *rate.Limiter
 
-	if matches == nil {
-		return nil, fmt.Errorf("invalid format: must be '2-4 char checktype hostname'")
+// setupRateLimit validates --rate and installs the global check limiter
+func setupRateLimit() error {
+	if checkRate < 0 {
+		return fmt.Errorf("invalid --rate %g: must not be negative", checkRate)
+	}
+	checkLimiter = nil
+	if checkRate > 0 {
+		checkLimiter = rate.NewLimiter(rate.Limit(checkRate), 1)
 	}
+	return nil
+}
 
-	return &core.Host{
-		CheckType: strings.ToUpper(matches[1]),
-		HostName:  matches[2],
-	}, nil
+// deadlinePassed reports whether a check started now would begin after the
+// --max-runtime deadline, counting any wait imposed by --rate. A zero
+// deadline never passes.
+func deadlinePassed(deadline time.Time) bool {
+	if deadline.IsZero() {
+		return false
+	}
+	start := time.Now()
+	if checkLimiter != nil {
+		// Work out the limiter's wait from its tokens; a reservation
+		// would spend one even when cancelled
+		if missing := 1 - checkLimiter.TokensAt(start); missing > 0 {
+			start = start.Add(time.Duration(missing / float64(checkLimiter.Limit()) * float64(time.Second)))
+		}
+	}
+	return !start.Before(deadline)
 }
 
-// Stream directly from config file to hosts to avoid keeping all lines in memory
-func hostsFromConfig(path string) ([]core.Host, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("open %s: %w", path, err)
+// saveResults hands the run's results to every registered result store
+// (history, notifications, ...). A failing store is logged and doesn't
+// stop the others or change the exit code.
+func saveResults(runStarted time.Time, results []core.Result) {
+	ctx := core.WithRunStarted(context.Background(), runStarted)
+	for _, s := range core.ResultStores() {
+		if err := s.Store.Save(ctx, results); err != nil {
+			log.Error().Err(err).Str("store", s.Name).Msg("failed to save results")
+		}
 	}
-	defer file.Close()
+}
 
-	hosts := make([]core.Host, 0, 128)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+// buildOptions turns the check flags into run-wide check options
+func buildOptions() (*core.Options, error) {
+	opts := core.DefaultOptions()
+	opts.Timeout = checkTimeout
+	opts.CAAppend = caAppend
+	if idleTimeout < 0 {
+		return nil, fmt.Errorf("invalid --idle-timeout %s: must not be negative", idleTimeout)
+	}
+	opts.KeepAlive = keepAlive
+	if dnsTTL < 0 {
+		return nil, fmt.Errorf("invalid --dns-ttl %s: must not be negative", dnsTTL)
+	}
+	if dnsTTL > 0 {
+		opts.Resolver = core.NewDNSCache(nil, dnsTTL)
+	}
+	opts.IdleTimeout = idleTimeout
+	size, err := core.ParseSize(maxBodySize)
+	if err != nil || size == 0 {
+		return nil, fmt.Errorf("invalid --max-body-size %q: want a positive size like 10MB", maxBodySize)
+	}
+	opts.MaxBodySize = size
+	if caBundle != "" {
+		pool, err := core.LoadCertPool(caBundle, caAppend)
+		if err != nil {
+			return nil, fmt.Errorf("load CA bundle %s: %w", caBundle, err)
 		}
-		h, err := parseHostString(line)
+		opts.RootCAs = pool
+	}
+	if socks5Proxy != "" {
+		dialer, err := core.NewSOCKS5Dialer(socks5Proxy)
 		if err != nil {
 			return nil, err
 		}
-		hosts = append(hosts, *h)
+		opts.Dialer = dialer
+	}
+	if maxProcs < 0 {
+		return nil, fmt.Errorf("invalid --max-procs %d: must not be negative", maxProcs)
+	}
+	opts.Processes = core.NewProcessPool(maxProcs)
+	opts.PingBin = binarySetting(pingBin, envPingBin)
+	if err := core.ValidateICMPMode(icmpMode); err != nil {
+		return nil, err
+	}
+	opts.ICMPMode = icmpMode
+	opts.PythonBin = binarySetting(pythonBin, envPythonBin)
+	opts.PwshBin = binarySetting(pwshBin, envPwshBin)
+	opts.SSHBin = binarySetting(sshBin, envSSHBin)
+	if updateBaseline && baselineDir == "" {
+		return nil, fmt.Errorf("--update-baseline needs --baseline-dir")
+	}
+	if dataFile != "" {
+		data, err := core.LoadScriptData(dataFile)
+		if err != nil {
+			return nil, fmt.Errorf("load --data-file: %w", err)
+		}
+		opts.Data = data
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scan %s: %w", path, err)
+	opts.BaselineDir = baselineDir
+	opts.UpdateBaseline = updateBaseline
+	if combSticky {
+		opts.CombSchemes = core.NewSchemeCache()
+	}
+	return opts, nil
+}
+
+// applyGlobalFlags applies the persistent flags that change how every
+// command reads check types
+func applyGlobalFlags(cmd *cobra.Command, args []string) error {
+	if err := applyAliases(cmd, args); err != nil {
+		return err
+	}
+	return applyDefaultPorts()
+}
+
+// applyDefaultPorts applies --default-port TYPE=N overrides. Aliases resolve,
+// so it runs after applyAliases.
+func applyDefaultPorts() error {
+	for _, spec := range portSpecs {
+		code, value, ok := strings.Cut(spec, "=")
+		port, err := strconv.Atoi(value)
+		if !ok || err != nil {
+			return fmt.Errorf("invalid --default-port %q: expected TYPE=N", spec)
+		}
+		if err := core.SetDefaultPort(code, port); err != nil {
+			return fmt.Errorf("invalid --default-port %q: %w", spec, err)
+		}
+	}
+	return nil
+}
+
+// applyAliases registers --alias NAME=CODE entries before any config is parsed
+func applyAliases(cmd *cobra.Command, args []string) error {
+	for _, spec := range aliasSpecs {
+		alias, code, ok := strings.Cut(spec, "=")
+		if !ok || !reCheckType.MatchString(alias) {
+			return fmt.Errorf("invalid --alias %q: expected NAME=CODE with a 2-4 character NAME", spec)
+		}
+		if err := core.AddCheckTypeAlias(alias, code); err != nil {
+			return fmt.Errorf("invalid --alias %q: %w", spec, err)
+		}
+	}
+	return nil
+}
+
+// applyCombFast switches COMB hosts to concurrent HEAD probing unless the
+// host line sets its own method/fast tokens
+func applyCombFast(hosts []core.Host) {
+	for i := range hosts {
+		if hosts[i].CheckType != "COMB" {
+			continue
+		}
+		if hosts[i].Tokens == nil {
+			hosts[i].Tokens = core.Tokens{}
+		}
+		// Hosts posting a payload keep their POST
+		if !hosts[i].Tokens.Has("method") && !hosts[i].Tokens.Has("body") {
+			hosts[i].Tokens.Add("method", "HEAD")
+		}
+		if !hosts[i].Tokens.Has("fast") {
+			hosts[i].Tokens.Add("fast", "true")
+		}
+	}
+}
+
+// hostLogger returns a logger carrying the standard per-host fields
+func hostLogger(host core.Host, checkLabel string) zerolog.Logger {
+	return log.With().
+		Str("host", host.HostName).
+		Str("label", host.DisplayName()).
+		Str("checkType", host.CheckType).
+		Str("checkLabel", checkLabel).
+		Logger()
+}
+
+// progressLog starts a routine progress line ("checking host", passes) at
+// info level. Under --failures-only it returns a nil event, which zerolog
+// treats as disabled, so only failures and the summary are logged.
+func progressLog(l zerolog.Logger) *zerolog.Event {
+	if failuresOnly {
+		return nil
 	}
-	return hosts, nil
+	return l.Info()
 }
 
-func runNetcheck(cmd *cobra.Command, args []string) error {
+// checkLabelFor returns the display name for a check type code
+func checkLabelFor(checkType string) string {
+	if label, ok := core.CheckTypeNames[checkType]; ok {
+		return label
+	}
+	return "Unknown"
+}
+
+// executeHost looks up and runs the host's check, logging progress and
+// returning its result
+func executeHost(host core.Host, opts *core.Options, retryOn core.RetryOn) core.Result {
+	started := time.Now()
+
+	checkFunc, ok := core.CheckTypes[host.CheckType]
+	if !ok && ignoreUnknown {
+		// Quietly skip check types this binary doesn't know (e.g. during rollout)
+		log.Debug().Str("host", host.HostName).Str("checkType", host.CheckType).Msg("skipping unknown check type")
+		return core.Result{Host: host, Status: core.StatusSkipped, SkipReason: skipUnknownType, Started: started}
+	}
+
+	hostLog := hostLogger(host, checkLabelFor(host.CheckType))
+	progressLog(hostLog).Msg("checking host")
+	if !ok {
+		hostLog.Error().Msg("unknown check type")
+		return core.Result{Host: host, Status: core.StatusUnknown, Err: fmt.Errorf("unknown check type %q", host.CheckType), Started: started}
+	}
+
+	passed, details, err := runCheck(checkFunc, host, opts, retryOn)
+	result := core.NewResult(host, passed, err, started, time.Since(started))
+	result.Details = details
+	result = core.EnforceMaxTime(result)
+	result.Maintenance = inMaintenance(host, started)
+	result.LowSeverity = belowMinSeverity(host)
+
+	logSecHeaders(hostLog, details)
+	logICMPMode(hostLog, details)
+
+	// Include whatever the check reported (exit code, timings, ...)
+	hostLog = hostLog.With().Fields(details).Logger()
+	var panicErr *core.PanicError
+	if errors.As(result.Err, &panicErr) {
+		hostLog.Error().Interface("panic", panicErr.Value).Str("stack", string(panicErr.Stack)).Msg("check panicked; continuing with the rest of the run")
+	}
+	if errors.Is(result.Err, core.ErrScriptAbandoned) {
+		hostLog.Warn().Msg("script ignored its deadline and was abandoned while still running")
+	}
+	if details["baselineChanged"] == true {
+		hostLog.Warn().Msg("response differs from baseline")
+	}
+	if _, ok := details["stickySchemeInvalidated"]; ok {
+		hostLog.Warn().Msg("sticky COMB scheme stopped answering; probing both schemes again")
+	}
+	if _, ok := details["dnsChanged"]; ok {
+		hostLog.Warn().Msg("DNS re-resolution changed the host's addresses")
+	}
+	if details["exitLevel"] == "warn" {
+		hostLog.Warn().Msg("script exit code maps to a warning")
+	}
+	switch {
+	case result.Maintenance && !result.Passed():
+		hostLog.Warn().Err(result.Err).Str("status", string(result.Status)).Msg("host failed check during maintenance window")
+	case result.LowSeverity && !result.Passed():
+		hostLog.Warn().Err(result.Err).Str("status", string(result.Status)).Str("severity", hostSeverityName(host)).Msg("host failed check below --min-severity")
+	case result.Status == core.StatusErrored:
+		hostLog.Error().Err(result.Err).Msg("check error")
+	case result.Status == core.StatusFailed:
+		hostLog.Error().Err(result.Err).Msg("host failed check")
+	default:
+		progressLog(hostLog).Msg("host passed check")
+	}
+	return result
+}
+
+// logSecHeaders logs the per-header breakdown of a secheaders= check at
+// debug level, one line per header
+func logSecHeaders(hostLog zerolog.Logger, details map[string]any) {
+	outcomes, ok := details["secHeaders"].(map[string]string)
+	if !ok {
+		return
+	}
+	names := make([]string, 0, len(outcomes))
+	for name := range outcomes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hostLog.Debug().Str("header", name).Bool("passed", outcomes[name] == "ok").Str("outcome", outcomes[name]).Msg("security header")
+	}
+}
+
+// logICMPMode logs at debug level which way an ICMP check pinged, and why
+// any native socket modes before it were passed over
+func logICMPMode(hostLog zerolog.Logger, details map[string]any) {
+	mode, ok := details["icmpMode"].(string)
+	if !ok {
+		return
+	}
+	event := hostLog.Debug().Str("mode", mode)
+	if fallback, ok := details["icmpFallback"].(string); ok {
+		event = event.Str("fallback", fallback)
+	}
+	event.Msg("ICMP mode")
+}
+
+// warnDuplicateIDs flags hosts sharing a check ID (same check, different
+// labels or timing tokens); their results can't be told apart by ID
+func warnDuplicateIDs(hosts []core.Host) {
+	seen := make(map[string]string, len(hosts))
+	for _, host := range hosts {
+		id := host.ID()
+		if first, ok := seen[id]; ok {
+			log.Warn().Str("id", id).Str("host", host.DisplayName()).Str("sameAs", first).Msg("duplicate check ID; set id= to tell them apart")
+			continue
+		}
+		seen[id] = host.DisplayName()
+	}
+}
+
+// checkRunnableHosts fails a config that would run no checks, saying
+// whether it was empty, all comments, or only unknown check types
+func checkRunnableHosts(hosts []core.Host, hasContent bool) error {
+	if !hasContent {
+		return fmt.Errorf("config is empty")
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("config has no hosts: every line is a comment or directive")
+	}
+	for _, host := range hosts {
+		if _, ok := core.CheckTypes[host.CheckType]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("config has no runnable hosts: all %d host(s) have unknown check types", len(hosts))
+}
+
+// summarize tallies results for the end-of-run summary
+func summarize(results []core.Result) Summary {
+	summary := Summary{ByTag: map[string]tagTally{}}
+	for _, r := range results {
+		tallyTags(summary.ByTag, r)
+		switch r.Status {
+		case core.StatusPassed:
+			summary.Passed++
+		case core.StatusFailed:
+			summary.Failed++
+		case core.StatusErrored:
+			summary.Errored++
+		case core.StatusUnknown:
+			summary.Unknown++
+		case core.StatusSkipped:
+			if r.SkipReason == skipUnknownType {
+				summary.SkippedUnknown++
+			}
+			if strings.HasPrefix(r.SkipReason, skipDependency) {
+				summary.SkippedDependency++
+			}
+			if r.SkipReason == skipDisabled {
+				summary.Disabled++
+			}
+			if strings.HasPrefix(r.SkipReason, skipNoAddress) {
+				summary.SkippedNoAddress++
+			}
+			if r.SkipReason == skipDeadline {
+				summary.SkippedDeadline++
+				summary.DeadlineHosts = append(summary.DeadlineHosts, r.Host.DisplayName())
+			}
+		}
+		if r.Status == core.StatusFailed || r.Status == core.StatusErrored {
+			// Failures inside a maintenance window or below --min-severity
+			// are tallied apart so they don't read as an outage
+			switch {
+			case r.LowSeverity:
+				summary.LowSeverity++
+				summary.LowSeverityHosts = append(summary.LowSeverityHosts, r.Host.DisplayName())
+			case r.Maintenance:
+				summary.Maintenance++
+				summary.MaintenanceHosts = append(summary.MaintenanceHosts, r.Host.DisplayName())
+			default:
+				summary.FailedHosts = append(summary.FailedHosts, r.Host.DisplayName())
+			}
+		}
+		// An unknown check type is a config mistake, never a warning
+		if r.Status == core.StatusUnknown {
+			summary.FailedHosts = append(summary.FailedHosts, r.Host.DisplayName())
+		}
+	}
+	summary.DegradedHosts = degradedHosts(results)
+	return summary
+}
+
+// runCheck executes a check, retrying failures whose error falls into one of
+// the configured transient classes. The details recorded by the final
+// attempt are returned alongside its outcome.
+func runCheck(checkFunc core.CheckFunc, host core.Host, opts *core.Options, retryOn core.RetryOn) (bool, map[string]any, error) {
+	// Resolve ${VAR} references only for execution; logs keep the config text
+	execHost := host.Expanded()
+
+	attempt := func() (bool, map[string]any, error) {
+		// Retries draw from the same budget so --rate bounds all outbound checks
+		if checkLimiter != nil {
+			if err := checkLimiter.Wait(context.Background()); err != nil {
+				return false, nil, err
+			}
+		}
+		ctx, details := core.WithDetails(context.Background())
+		passed, err := core.CallCheck(ctx, checkFunc, execHost, opts)
+		return passed, details(), err
+	}
+
+	passed, details, err := attempt()
+	for n := 1; n <= retries && !passed && retryOn.ShouldRetry(err); n++ {
+		log.Warn().Err(err).Str("host", host.HostName).Str("checkType", host.CheckType).Int("attempt", n).Int("retries", retries).Msg("retrying check")
+		time.Sleep(retryDelay)
+		passed, details, err = attempt()
+	}
+	return passed, details, err
+}
+
+func runNetcheck(cmd *cobra.Command, args []string) (runErr error) {
+	retryOn, err := core.ParseRetryOn(retryOnSpec)
+	if err != nil {
+		return fmt.Errorf("invalid --retry-on: %w", err)
+	}
+	outputs, err := parseOutputs(outputFormat)
+	if err != nil {
+		return fmt.Errorf("invalid --output: %w", err)
+	}
+	if err := validatePlanFormat(printPlan); err != nil {
+		return err
+	}
+	if err := validateSort(sortBy); err != nil {
+		return err
+	}
+	if err := configureSyntax(commentFlag, fieldSepFlag); err != nil {
+		return err
+	}
+	if err := setupRateLimit(); err != nil {
+		return err
+	}
+	if maintenanceWindows, err = parseMaintenanceFlags(maintSpecs); err != nil {
+		return err
+	}
+	if minSeverity, err = parseMinSeverity(minSeverityArg); err != nil {
+		return err
+	}
+	if repeatCount < 1 {
+		return fmt.Errorf("invalid --repeat %d: must be at least 1", repeatCount)
+	}
+	if maxRuntime < 0 {
+		return fmt.Errorf("invalid --max-runtime %s: must not be negative", maxRuntime)
+	}
+	if minSuccess < 0 || minSuccess > 1 {
+		return fmt.Errorf("invalid --min-success-ratio %g: must be between 0 and 1", minSuccess)
+	}
+	if probeMode {
+		if err := validateProbeQuorum(probeQuorum); err != nil {
+			return err
+		}
+		if repeatCount > 1 || printPlan != "" || tuiMode {
+			return fmt.Errorf("--probe can't be combined with --repeat, --print-plan, or --tui")
+		}
+		// A probe never waits on a terminal
+		batchMode = true
+	}
+	if err := validateWait(); err != nil {
+		return err
+	}
+	if dualStack && socks5Proxy != "" {
+		// The proxy resolves names, so the family can't be chosen here
+		return fmt.Errorf("--dual-stack can't be combined with --socks5")
+	}
+	if keepHostsLog && (!redactHosts || transcriptPath == "") {
+		return fmt.Errorf("--unredacted-transcript needs --redact and --log")
+	}
+	if countOnly {
+		if err := validateCountOnly(outputs); err != nil {
+			return err
+		}
+		batchMode = true
+	}
+
+	var notifyTemplate *template.Template
+	if notifyURL != "" || notifyTmpl != "" {
+		if notifyURL == "" {
+			return fmt.Errorf("--notify-template needs --notify-url")
+		}
+		if notifyTemplate, err = loadNotifyTemplate(notifyTmpl); err != nil {
+			return err
+		}
+	}
+
+	var hook *changeHook
+	if onChange != "" {
+		if hook, err = newChangeHook(onChange, onChangeWait); err != nil {
+			return err
+		}
+	}
+	preHook, err := newRunHook("pre-hook", preHookCmd, hookTimeout)
+	if err != nil {
+		return err
+	}
+	postHook, err := newRunHook("post-hook", postHookCmd, hookTimeout)
+	if err != nil {
+		return err
+	}
+	syslogSink, err := newSyslogStore(syslogAddr, syslogProto, syslogFacility, syslogTag)
+	if err != nil {
+		return err
+	}
+
+	// Flags are valid - runtime errors below shouldn't print usage
+	cmd.SilenceUsage = true
+
+	// Load secrets before anything is logged so they can be redacted
+	var secrets []string
+	if secretsFile != "" {
+		secrets, err = loadSecretsFile(secretsFile)
+		if err != nil {
+			return fmt.Errorf("load secrets: %w", err)
+		}
+	}
+	// Hostnames are registered once the config is parsed
+	var hostMask *hostRedactor
+	if redactHosts {
+		if hostMask, err = newHostRedactor(); err != nil {
+			return fmt.Errorf("set up --redact: %w", err)
+		}
+	}
+
+	// A config directory stands in for the config path in logs and reports
+	if configDir != "" {
+		cfgFile = configDir
+	}
+
+	// Notifications go out with the other result stores after the run
+	var notifier core.ResultStore = core.NopStore{}
+	if notifyTemplate != nil {
+		notifier = &notifyStore{url: notifyURL, tmpl: notifyTemplate, config: cfgFile, secrets: secrets, hosts: hostMask}
+	}
+	core.RegisterResultStore("notify", notifier)
+	var desktop core.ResultStore = core.NopStore{}
+	if notifyDesktop {
+		desktop = &desktopNotifyStore{config: cfgFile, secrets: secrets, hosts: hostMask}
+	}
+	core.RegisterResultStore("desktop", desktop)
+	if syslogSink != nil {
+		syslogSink.secrets, syslogSink.hosts = secrets, hostMask
+		core.RegisterResultStore("syslog", syslogSink)
+	}
+
 	// Setup logging
-	consoleWriter := zerolog.ConsoleWriter{Out: os.Stderr}
+	consoleWriter := zerolog.ConsoleWriter{Out: hostMask.Writer(newRedactWriter(os.Stderr, secrets))}
 
 	var logWriter io.Writer = consoleWriter
+	var quietWriter io.Writer = io.Discard
 	var transcriptFile *os.File
 
 	// If transcript logging is enabled, write to both console and file
 	if transcriptPath != "" {
-		var err error
 		transcriptFile, err = os.OpenFile(transcriptPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
 			log.Fatal().Err(err).Str("transcript", transcriptPath).Msg("failed to open transcript file")
@@ -106,50 +805,309 @@ func runNetcheck(cmd *cobra.Command, args []string) error {
 		defer transcriptFile.Close()
 
 		// Create multi-writer to output to both console and file
-		logWriter = io.MultiWriter(consoleWriter, transcriptFile)
+		quietWriter = newRedactWriter(transcriptFile, secrets)
+		if !keepHostsLog {
+			quietWriter = hostMask.Writer(quietWriter)
+		}
+		logWriter = io.MultiWriter(consoleWriter, quietWriter)
+	}
+
+	// A probe keeps stderr for its one-line verdict, and --count-only prints
+	// just its summary line; only fatal errors reach the console (the
+	// transcript still gets everything)
+	if probeMode || countOnly {
+		console := &zerolog.FilteredLevelWriter{Writer: zerolog.LevelWriterAdapter{Writer: consoleWriter}, Level: zerolog.FatalLevel}
+		logWriter = zerolog.MultiLevelWriter(console, quietWriter)
 	}
 
 	log.Logger = log.Output(logWriter)
+
+	// Structured results go to stdout or files, with secrets masked like the logs
+	stdout := hostMask.Writer(newRedactWriter(os.Stdout, secrets))
+	reports, err := openReports(outputs, stdout, secrets, hostMask)
+	if err != nil {
+		return err
+	}
+	defer reports.Close()
 	log.Info().Msg("starting up")
 
-	hosts, err := hostsFromConfig(cfgFile)
+	// The summary file is written however the run ends from here on, so a
+	// status page sees failed and aborted runs too
+	var summary Summary
+	if summaryJSON != "" {
+		started := time.Now()
+		defer func() {
+			if printPlan != "" {
+				return
+			}
+			rec := newSummaryFileRecord(summary, started, time.Now(), runErr)
+			rec.Error = hostMask.Replace(rec.Error)
+			if err := writeSummaryFile(summaryJSON, rec); err != nil {
+				log.Error().Err(err).Str("path", summaryJSON).Msg("failed to write summary file")
+			}
+		}()
+	}
+
+	// Options come first: a config fetched from a URL uses the CA and proxy
+	// settings too
+	opts, err := buildOptions()
 	if err != nil {
-		log.Fatal().Err(err).Str("config", cfgFile).Msg("failed to load config")
+		log.Error().Err(err).Msg("invalid check options")
+		reports.Error("config", err)
+		cmd.SilenceErrors = !probeMode && !countOnly
+		return &ExitError{Code: ExitConfigError, Err: err}
 	}
 
-	for _, host := range hosts {
-		checkLabel := "Unknown"
-		if label, ok := core.CheckTypeNames[host.CheckType]; ok {
-			checkLabel = label
-		}
+	var hosts []core.Host
+	var hasContent bool
+	if configDir != "" {
+		hosts, hasContent, err = hostsFromDir(configDir, configFormat)
+	} else {
+		hosts, hasContent, err = hostsFromConfig(cfgFile, configFormat, opts)
+	}
+	if err == nil && requireHosts {
+		err = checkRunnableHosts(hosts, hasContent)
+	}
+	if err == nil {
+		err = validateMaintenanceTokens(hosts)
+	}
+	if err == nil {
+		err = validateSeverityTokens(hosts)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("config", cfgFile).Msg("failed to load config")
+		reports.Error("config", err)
+		// Already logged - don't print it a second time (a probe's console
+		// log is muted, so it prints the error as its verdict)
+		cmd.SilenceErrors = !probeMode && !countOnly
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("load config %s: %w", cfgFile, err)}
+	}
 
-		log.Info().Str("host", host.HostName).Str("checkType", host.CheckType).Str("checkLabel", checkLabel).Msg("checking host")
-		checkFunc, ok := core.CheckTypes[host.CheckType]
-		if !ok {
-			log.Error().Str("host", host.HostName).Str("checkType", host.CheckType).Str("checkLabel", checkLabel).Msg("unknown check type")
-			continue
+	if hostMask != nil {
+		hostMask.AddHosts(hosts)
+		if keepHostsLog {
+			logRedactMapping(quietWriter, hostMask)
 		}
+	}
 
-		passed, err := checkFunc(host)
-		if err != nil {
-			log.Error().Err(err).Str("host", host.HostName).Str("checkType", host.CheckType).Str("checkLabel", checkLabel).Msg("check error")
-			continue
+	warnDuplicateIDs(hosts)
+
+	if combFast {
+		applyCombFast(hosts)
+	}
+	hosts = core.SplitDualStack(hosts, dualStack)
+
+	if printPlan != "" {
+		log.Info().Int("hostCount", len(hosts)).Str("config", cfgFile).Msg("config parsed")
+		return writePlan(stdout, cfgFile, hosts, opts)
+	}
+
+	// Overridden binaries are checked up front, not once per host
+	if err := validateBinaries(hosts, opts); err != nil {
+		log.Error().Err(err).Msg("invalid check binary")
+		reports.Error("config", err)
+		cmd.SilenceErrors = !probeMode && !countOnly
+		return &ExitError{Code: ExitConfigError, Err: err}
+	}
+
+	// The post-hook runs however the run ends from here on, even when the
+	// pre-hook aborts it (e.g. to tear down a half-opened VPN)
+	aborted := false
+	if postHook != nil {
+		defer func() {
+			// Failures are logged; they don't change the exit code
+			_ = postHook.Run(postHookEnv(summary, aborted))
+		}()
+	}
+	if preHook != nil {
+		if err := preHook.Run(nil); err != nil {
+			aborted = true
+			reports.Error("pre-hook", err)
+			cmd.SilenceErrors = !probeMode && !countOnly
+			return err
+		}
+	}
+	// Checked after the pre-hook, which may be what brings the network up
+	if gatewayCheck || gatewayAddr != "" {
+		if err := checkGateway(opts); err != nil {
+			aborted = true
+			reports.Error("gateway", err)
+			cmd.SilenceErrors = !probeMode && !countOnly
+			return err
 		}
+	}
 
-		if !passed {
-			log.Error().Str("host", host.HostName).Str("checkType", host.CheckType).Str("checkLabel", checkLabel).Msg("host failed check")
+	// The live view owns the terminal while checks run; console logs are
+	// held back (the transcript still gets them) and resume for the summary
+	var view *liveView
+	if tuiMode {
+		if !reports.StructuredStdout() && isTerminal(os.Stdout) {
+			view = newLiveView(stdout, hosts)
+			log.Logger = log.Output(quietWriter)
 		} else {
-			log.Info().Str("host", host.HostName).Str("checkType", host.CheckType).Str("checkLabel", checkLabel).Msg("host passed check")
+			log.Warn().Msg("--tui needs console output on a terminal, falling back to log output")
 		}
 	}
+
+	runStarted := time.Now()
+	var deadline time.Time
+	if maxRuntime > 0 {
+		deadline = runStarted.Add(maxRuntime)
+	}
+	// runOnce checks every host once. Results are streamed to the reports
+	// as they arrive, unless the caller reports them itself.
+	runOnce := func(stream bool) []core.Result {
+		runResults := make([]core.Result, 0, len(hosts))
+		outcomes := make(map[string]core.Status, len(hosts))
+		for i, host := range hosts {
+			if view != nil {
+				view.Checking(i)
+			}
+			var result core.Result
+			if host.Disabled {
+				hostLog := hostLogger(host, checkLabelFor(host.CheckType))
+				hostLog.Info().Msg("skipping disabled host")
+				result = core.Result{Host: host, Status: core.StatusSkipped, SkipReason: skipDisabled, Started: time.Now()}
+			} else if deadlinePassed(deadline) {
+				// Checks in flight finish; the rest are reported, not run
+				hostLog := hostLogger(host, checkLabelFor(host.CheckType))
+				hostLog.Warn().Dur("maxRuntime", maxRuntime).Msg("skipping host, run deadline exceeded")
+				result = core.Result{Host: host, Status: core.StatusSkipped, SkipReason: skipDeadline, Started: time.Now()}
+			} else if dep, failed := failedDependency(host, outcomes); failed {
+				// Suppress cascading failures behind a broken prerequisite
+				hostLog := hostLogger(host, checkLabelFor(host.CheckType))
+				hostLog.Warn().Str("dependency", dep).Msg("skipping host, dependency failed")
+				result = core.Result{Host: host, Status: core.StatusSkipped, SkipReason: fmt.Sprintf("%s: %s", skipDependency, dep), Started: time.Now()}
+			} else if family, missing := missingFamily(host, opts); missing {
+				hostLog := hostLogger(host, checkLabelFor(host.CheckType))
+				hostLog.Info().Str("family", family).Msg("skipping host, no address in family")
+				result = core.Result{Host: host, Status: core.StatusSkipped, SkipReason: fmt.Sprintf("%s: %s", skipNoAddress, family), Started: time.Now()}
+			} else {
+				result = executeHost(host, opts, retryOn)
+			}
+			outcomes[host.DisplayName()] = result.Status
+			// depends= on a dual-stack host is met when either family passes
+			if host.DualStack != "" && outcomes[host.DualStack] != core.StatusPassed {
+				outcomes[host.DualStack] = result.Status
+			}
+			if view != nil {
+				view.Update(i, result)
+			}
+			if stream {
+				reports.Result(result)
+			}
+			if hook != nil {
+				hook.Observe(result)
+			}
+			runResults = append(runResults, result)
+		}
+		return runResults
+	}
+
+	var results []core.Result
+	var runs [][]core.Result
+	var waitErr error
+	if waitHealthy {
+		var attempts int
+		var healthy bool
+		results, attempts, healthy = waitForHealthy(runOnce)
+		for _, result := range results {
+			reports.Result(result)
+		}
+		runs = append(runs, results)
+		if !healthy {
+			waitErr = &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("not healthy after %s (%d attempts)", waitTimeout, attempts)}
+		}
+	}
+	for run := 1; !waitHealthy && run <= repeatCount; run++ {
+		if repeatCount > 1 {
+			log.Info().Int("run", run).Int("of", repeatCount).Msg("starting run")
+		}
+		if view != nil {
+			view.StartRun(run, repeatCount)
+		}
+		runResults := runOnce(true)
+		runs = append(runs, runResults)
+		results = append(results, runResults...)
+	}
+	if view != nil {
+		log.Logger = log.Output(logWriter)
+	}
+	// Reports and the summary follow --sort; stores and aggregates keep
+	// execution order
+	sorted := sortResults(results, sortBy)
+	summary = summarize(sorted)
 	log.Info().Int("hostCount", len(hosts)).Str("config", cfgFile).Msg("config parsed")
+	log.Info().Int("passed", summary.Passed).Int("failed", summary.Failed).Int("errored", summary.Errored).Int("unknown", summary.Unknown).Int("skippedUnknown", summary.SkippedUnknown).Int("skippedDependency", summary.SkippedDependency).Int("skippedDeadline", summary.SkippedDeadline).Int("skippedNoAddress", summary.SkippedNoAddress).Int("disabled", summary.Disabled).Strs("deadlineHosts", summary.DeadlineHosts).Strs("failedHosts", summary.FailedHosts).Int("maintenance", summary.Maintenance).Strs("maintenanceHosts", summary.MaintenanceHosts).Int("lowSeverity", summary.LowSeverity).Strs("lowSeverityHosts", summary.LowSeverityHosts).Strs("degradedHosts", summary.DegradedHosts).Msg("run summary")
+	if len(summary.ByTag) > 0 {
+		log.Info().Str("byTag", tagSummaryLine(summary.ByTag)).Msg("summary by tag")
+	}
+	for _, name := range summary.DegradedHosts {
+		log.Warn().Str("host", name).Msg("dual-stack host degraded: reachable over only one address family")
+	}
+
+	// Per-host stability and error budgets across repeated runs
+	var aggregates []hostAggregate
+	unstable, exhausted := 0, 0
+	if repeatCount > 1 || hasBudgets(hosts) {
+		minRatio := minSuccess
+		if repeatCount == 1 {
+			// A single run has no stability to judge, only budgets
+			minRatio = 0
+		}
+		aggregates = aggregateRuns(runs)
+		unstable, exhausted = logAggregates(aggregates, minRatio)
+		summary.BudgetsExhausted = exhausted
+		summary.Aggregates = aggregates
+	}
+
+	if err := reports.Finish(sorted, summary); err != nil {
+		return err
+	}
+
+	saveResults(runStarted, results)
+
+	if failedConfig != "" {
+		if err := writeFailedConfig(failedConfig, hosts, results); err != nil {
+			log.Error().Err(err).Str("path", failedConfig).Msg("failed to write failed-host config")
+		}
+	}
+
+	if countOnly {
+		fmt.Fprintln(stdout, countOnlyLine(summary))
+	}
 
 	// Only prompt if not in batch mode
 	if !batchMode {
-		fmt.Print("Press any key to exit...")
-		var input string
-		fmt.Scanln(&input)
+		// Keep stdout clean for structured output
+		var promptOut io.Writer = os.Stdout
+		if reports.StructuredStdout() {
+			promptOut = os.Stderr
+		}
+		waitForKey(promptOut)
 	}
 
+	if probeMode {
+		return probeVerdict(results, summary, probeQuorum)
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+	if unstable > 0 {
+		return &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("%d host(s) below minimum success ratio %g over %d runs", unstable, minSuccess, repeatCount)}
+	}
+	if exhausted > 0 {
+		return &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("%d host(s) exhausted their error budget", exhausted)}
+	}
+	// Across repeated runs --min-success-ratio judges failures, and
+	// budget= hosts are judged by their budgets; neither looks at unknown
+	// check types, which always fail the run
+	failing := summary.Unknown
+	if repeatCount == 1 {
+		failing = max(failing, len(unbudgetedHosts(summary.FailedHosts, hosts)))
+	}
+	if failing > 0 {
+		return &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("%d check(s) failed", failing)}
+	}
 	return nil
 }
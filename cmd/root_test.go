@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+func TestExecuteHostSurvivesPanickingCheck(t *testing.T) {
+	ran := false
+	core.CheckTypes["TPAN"] = func(ctx context.Context, host core.Host, opts *core.Options) (bool, error) {
+		panic("boom")
+	}
+	core.CheckTypes["TOK"] = func(ctx context.Context, host core.Host, opts *core.Options) (bool, error) {
+		ran = true
+		return true, nil
+	}
+	t.Cleanup(func() {
+		delete(core.CheckTypes, "TPAN")
+		delete(core.CheckTypes, "TOK")
+	})
+
+	hosts := []core.Host{
+		{CheckType: "TPAN", HostName: "panics.test"},
+		{CheckType: "TOK", HostName: "passes.test"},
+	}
+	var results []core.Result
+	for _, host := range hosts {
+		results = append(results, executeHost(host, core.DefaultOptions(), core.RetryOn{}))
+	}
+
+	if !ran {
+		t.Fatal("check after the panicking one didn't run")
+	}
+	if results[0].Status != core.StatusErrored {
+		t.Errorf("panicking check status = %q, want %q", results[0].Status, core.StatusErrored)
+	}
+	var panicErr *core.PanicError
+	if !errors.As(results[0].Err, &panicErr) {
+		t.Fatalf("panicking check error = %v, want a *core.PanicError", results[0].Err)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Error("PanicError.Stack is empty")
+	}
+	if results[1].Status != core.StatusPassed {
+		t.Errorf("normal check status = %q, want %q", results[1].Status, core.StatusPassed)
+	}
+}
+
+func TestUnknownCheckTypeFailsRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netcheck.txt")
+	if err := os.WriteFile(path, []byte("XYZ host.invalid\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"-b", "-f", path})
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+
+	err := rootCmd.Execute()
+	if code := ExitCode(err); code != ExitChecksFailed {
+		t.Errorf("exit code = %d (err %v), want %d", code, err, ExitChecksFailed)
+	}
+}
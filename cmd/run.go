@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run \"TYPE host [key=value ...]\"",
+	Short: "Run a single check ad hoc without a config file",
+	Long: `Run one check given as a config line, print the result, and exit with
+a code reflecting pass (0) or fail (1). As with a config run, a failure
+inside the host's maint= window exits 0. An invalid host spec exits with 2.
+
+The spec accepts everything a config line does, including per-host tokens:
+
+  netcheck run "http example.com timeout=10s maxtime=500ms"
+  netcheck run htps api.internal cacert=ca.pem`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSingle,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().StringVarP(&outputFormat, "output", "o", outputConsole, "result output on stdout: console, json or ndjson (one JSON object)")
+	addCheckFlags(runCmd.Flags())
+}
+
+func runSingle(cmd *cobra.Command, args []string) error {
+	retryOn, err := core.ParseRetryOn(retryOnSpec)
+	if err != nil {
+		return fmt.Errorf("invalid --retry-on: %w", err)
+	}
+	if err := validateOutput(outputFormat); err != nil {
+		return err
+	}
+	if err := setupRateLimit(); err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	var secrets []string
+	if secretsFile != "" {
+		secrets, err = loadSecretsFile(secretsFile)
+		if err != nil {
+			return fmt.Errorf("load secrets: %w", err)
+		}
+	}
+	var hostMask *hostRedactor
+	if redactHosts {
+		if hostMask, err = newHostRedactor(); err != nil {
+			return fmt.Errorf("set up --redact: %w", err)
+		}
+	}
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: hostMask.Writer(newRedactWriter(os.Stderr, secrets))})
+	stdout := hostMask.Writer(newRedactWriter(os.Stdout, secrets))
+
+	// Args are joined so the spec can be quoted or passed as separate words
+	host, err := parseHostString(strings.Join(args, " "))
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("invalid host spec: %w", err)}
+	}
+	if _, ok := core.CheckTypes[host.CheckType]; !ok {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("invalid host spec: unknown check type %q", host.CheckType)}
+	}
+	hosts, err := expandHostRanges([]core.Host{*host})
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("invalid host spec: %w", err)}
+	}
+	if len(hosts) > 1 {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("invalid host spec: %s expands to %d hosts; run checks one, so list ranges in a config", host.HostName, len(hosts))}
+	}
+	host = &hosts[0]
+	hostMask.AddHosts(hosts)
+	if err := validateMaintenanceTokens(hosts); err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("invalid host spec: %w", err)}
+	}
+	if err := validateSeverityTokens(hosts); err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("invalid host spec: %w", err)}
+	}
+	if host.Tokens.Get("ipv") == core.FamilyBoth && core.SupportsIPFamily(host.CheckType) {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("invalid host spec: ipv=both runs two checks; use ipv=4 or ipv=6 with run")}
+	}
+	if combFast {
+		applyCombFast(hosts)
+	}
+
+	opts, err := buildOptions()
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: err}
+	}
+	if err := validateBinaries(hosts, opts); err != nil {
+		return &ExitError{Code: ExitConfigError, Err: err}
+	}
+
+	result := executeHost(hosts[0], opts, retryOn)
+	if outputFormat == outputConsole {
+		line := fmt.Sprintf("%s %s %s %.1fms", strings.ToUpper(string(result.Status)), result.Host.CheckType, result.Host.DisplayName(), durationMs(result.Duration))
+		if result.Err != nil {
+			line += ": " + result.Err.Error()
+		}
+		fmt.Fprintln(stdout, line)
+	} else if err := writeNDJSON(stdout, result); err != nil {
+		return err
+	}
+
+	// Like the root command, a failure in a maintenance window doesn't fail
+	// the run
+	failed := result.Status == core.StatusFailed || result.Status == core.StatusErrored
+	if failed && !result.Maintenance && !result.LowSeverity {
+		// The result line already explains the failure
+		cmd.SilenceErrors = true
+		return &ExitError{Code: ExitChecksFailed, Err: fmt.Errorf("check %s", result.Status)}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package cmd
+
+import "testing"
+
+func TestRunRejectsUnknownCheckType(t *testing.T) {
+	rootCmd.SetArgs([]string{"run", "XYZ host.invalid"})
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+
+	err := rootCmd.Execute()
+	if code := ExitCode(err); code != ExitConfigError {
+		t.Errorf("exit code = %d (err %v), want %d", code, err, ExitConfigError)
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// runHook is a --pre-hook or --post-hook command run around the whole run
+type runHook struct {
+	name    string // flag name, for logs and errors
+	args    []string
+	timeout time.Duration
+}
+
+// newRunHook parses a hook command line like --on-change: arguments are
+// split like config fields and never go through a shell. An empty command
+// means no hook.
+func newRunHook(name, command string, timeout time.Duration) (*runHook, error) {
+	if command == "" {
+		return nil, nil
+	}
+	args, err := splitFields(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", name, err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid --%s: empty command", name)
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("invalid --hook-timeout %s: must be positive", timeout)
+	}
+	return &runHook{name: name, args: args, timeout: timeout}, nil
+}
+
+// Run executes the hook with extra environment variables and logs its
+// combined output, so it lands in the transcript
+func (h *runHook) Run(env []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.args[0], h.args[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+
+	hookLog := log.With().Str("hook", h.name).Str("output", strings.TrimSpace(string(output))).Logger()
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		hookLog.Error().Dur("timeout", h.timeout).Msg("run hook timed out")
+		return fmt.Errorf("--%s timed out after %s", h.name, h.timeout)
+	case err != nil:
+		hookLog.Error().Err(err).Msg("run hook failed")
+		return fmt.Errorf("--%s failed: %w", h.name, err)
+	}
+	hookLog.Info().Msg("run hook ran")
+	return nil
+}
+
+// postHookEnv describes the finished run to --post-hook. Failures count
+// failed, errored, and unknown results; status is "passed" when there were
+// none, or "aborted" when the pre-hook stopped the run.
+func postHookEnv(summary Summary, aborted bool) []string {
+	failed := summary.Failed + summary.Errored + summary.Unknown
+	skipped := summary.SkippedUnknown + summary.SkippedDependency + summary.SkippedDeadline + summary.SkippedNoAddress + summary.Disabled
+	status := "passed"
+	switch {
+	case aborted:
+		status = "aborted"
+	case failed > 0:
+		status = "failed"
+	}
+	return []string{
+		"NETCHECK_STATUS=" + status,
+		"NETCHECK_PASSED=" + strconv.Itoa(summary.Passed),
+		"NETCHECK_FAILED=" + strconv.Itoa(failed),
+		"NETCHECK_SKIPPED=" + strconv.Itoa(skipped),
+		"NETCHECK_TOTAL=" + strconv.Itoa(summary.Passed+failed+skipped),
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// minRedactLength is the shortest secret value that gets redacted from
+// output; shorter values would mangle unrelated log text
+const minRedactLength = 4
+
+// loadSecretsFile reads a dotenv-style file (KEY=VALUE per line, optional
+// "export " prefix and quotes) into the process environment so that ${VAR}
+// references in the config and script checks can use it. Values already set
+// in the OS environment are overridden. The loaded values are returned so
+// they can be redacted from output.
+func loadSecretsFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var values []string
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			// Don't echo the line - it may contain a secret
+			return nil, fmt.Errorf("%s line %d: expected KEY=VALUE", path, lineNum)
+		}
+		value = unquoteSecret(strings.TrimSpace(value))
+
+		if err := os.Setenv(key, value); err != nil {
+			return nil, fmt.Errorf("%s line %d: set %s: %w", path, lineNum, key, err)
+		}
+		if len(value) >= minRedactLength {
+			values = append(values, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// unquoteSecret strips matching single or double quotes from a value
+func unquoteSecret(value string) string {
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '"' && value[len(value)-1] == '"':
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+			return value[1 : len(value)-1]
+		case value[0] == '\'' && value[len(value)-1] == '\'':
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+// redactWriter masks secret values in everything written through it. Both
+// the raw value and its JSON-escaped form are masked so JSON transcripts
+// are covered too.
+type redactWriter struct {
+	w        io.Writer
+	replacer *strings.Replacer
+}
+
+func newRedactWriter(w io.Writer, secrets []string) io.Writer {
+	if len(secrets) == 0 {
+		return w
+	}
+	var pairs []string
+	for _, secret := range secrets {
+		pairs = append(pairs, secret, "***")
+		if escaped := strconv.Quote(secret); escaped[1:len(escaped)-1] != secret {
+			pairs = append(pairs, escaped[1:len(escaped)-1], "***")
+		}
+	}
+	return &redactWriter{w: w, replacer: strings.NewReplacer(pairs...)}
+}
+
+func (r *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, r.replacer.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	// Report the original length so callers don't treat redaction as a short write
+	return len(p), nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// minSeverity is the parsed --min-severity: failures of hosts below it are
+// logged but don't alert or affect the exit code
+var minSeverity = core.SeverityInfo
+
+// parseMinSeverity parses the --min-severity flag
+func parseMinSeverity(v string) (core.Severity, error) {
+	s, err := core.ParseSeverity(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --min-severity %q: want critical, warning, or info", v)
+	}
+	return s, nil
+}
+
+// validateSeverityTokens checks every host's severity= so a typo is a
+// config error rather than a host that silently never alerts
+func validateSeverityTokens(hosts []core.Host) error {
+	for _, host := range hosts {
+		if _, err := host.Severity(); err != nil {
+			return fmt.Errorf("host %s: %w", host.DisplayName(), err)
+		}
+	}
+	return nil
+}
+
+// belowMinSeverity reports whether the host's failures fall under
+// --min-severity
+func belowMinSeverity(host core.Host) bool {
+	s, err := host.Severity()
+	return err == nil && s < minSeverity
+}
+
+// hostSeverityName is the host's severity for output, critical when unset
+func hostSeverityName(host core.Host) string {
+	s, err := host.Severity()
+	if err != nil {
+		return host.Tokens.Get("severity")
+	}
+	return s.String()
+}
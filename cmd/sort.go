@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// --sort keys for the batched outputs
+const (
+	sortStatus  = "status"
+	sortLatency = "latency"
+	sortHost    = "host"
+	sortType    = "type"
+)
+
+// statusRank orders --sort status: problems first, passes last
+var statusRank = map[core.Status]int{
+	core.StatusErrored: 0,
+	core.StatusFailed:  1,
+	core.StatusUnknown: 2,
+	core.StatusSkipped: 3,
+	core.StatusPassed:  4,
+}
+
+// validateSort checks the --sort key; empty keeps execution order
+func validateSort(by string) error {
+	switch by {
+	case "", sortStatus, sortLatency, sortHost, sortType:
+		return nil
+	}
+	return fmt.Errorf("invalid --sort %q: want status, latency, host, or type", by)
+}
+
+// sortResults returns the results ordered by the --sort key, with ties
+// broken by host and then execution order. The slice passed in keeps its
+// order, so stores and aggregates still see execution order.
+func sortResults(results []core.Result, by string) []core.Result {
+	if by == "" {
+		return results
+	}
+	sorted := slices.Clone(results)
+	slices.SortStableFunc(sorted, func(a, b core.Result) int {
+		var c int
+		switch by {
+		case sortStatus:
+			c = cmp.Compare(statusRank[a.Status], statusRank[b.Status])
+		case sortLatency:
+			// Slowest first
+			c = cmp.Compare(b.Duration, a.Duration)
+		case sortType:
+			c = cmp.Compare(a.Host.CheckType, b.Host.CheckType)
+		}
+		if c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Host.DisplayName(), b.Host.DisplayName())
+	})
+	return sorted
+}
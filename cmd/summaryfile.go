@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Run statuses written to --summary-json
+const (
+	runStatusPassed = "passed"
+	runStatusFailed = "failed"
+	runStatusError  = "error"
+)
+
+// summaryFileRecord is the run-level rollup written to --summary-json: no
+// per-host detail, just what a status page needs
+type summaryFileRecord struct {
+	Status            string    `json:"status"`
+	Total             int       `json:"total"`
+	Passed            int       `json:"passed"`
+	Failed            int       `json:"failed"`
+	Errored           int       `json:"errored"`
+	Unknown           int       `json:"unknown"`
+	Skipped           int       `json:"skipped"`
+	SkippedDependency int       `json:"skippedDependency"`
+	Warnings          int       `json:"warnings"`
+	Started           time.Time `json:"started"`
+	Finished          time.Time `json:"finished"`
+	DurationMs        float64   `json:"durationMs"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// newSummaryFileRecord builds the rollup. Warnings are failures inside a
+// maintenance window or below --min-severity, which don't fail the run. A run that ended in an
+// error before any check ran (bad config, failed pre-hook) has status
+// "error"; otherwise any other failed check or failed gate makes it
+// "failed".
+func newSummaryFileRecord(s Summary, started, finished time.Time, runErr error) summaryFileRecord {
+	rec := summaryFileRecord{
+		Status:            runStatusPassed,
+		Total:             s.Passed + s.Failed + s.Errored + s.Unknown,
+		Passed:            s.Passed,
+		Failed:            s.Failed,
+		Errored:           s.Errored,
+		Unknown:           s.Unknown,
+		Skipped:           s.SkippedUnknown + s.SkippedDependency + s.SkippedDeadline + s.SkippedNoAddress + s.Disabled,
+		SkippedDependency: s.SkippedDependency,
+		Warnings:          s.Maintenance + s.LowSeverity,
+		Started:           started.UTC(),
+		Finished:          finished.UTC(),
+		DurationMs:        durationMs(finished.Sub(started)),
+	}
+	switch {
+	case runErr != nil && rec.Total+rec.Skipped == 0:
+		rec.Status = runStatusError
+	case runErr != nil || len(s.FailedHosts)+s.Unknown > 0:
+		rec.Status = runStatusFailed
+	}
+	if runErr != nil {
+		rec.Error = runErr.Error()
+	}
+	return rec
+}
+
+// writeSummaryFile replaces path with the run's rollup
+func writeSummaryFile(path string, rec summaryFileRecord) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rec)
+	})
+}
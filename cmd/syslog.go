@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// Syslog severities (RFC 5424) used for results
+const (
+	syslogSevErr     = 3
+	syslogSevWarning = 4
+	syslogSevNotice  = 5
+	syslogSevInfo    = 6
+)
+
+// syslogFacilities maps --syslog-facility names to facility codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSender delivers messages to a syslog server; dialSyslog opens one
+// with log/syslog, or a plain network connection where that's unavailable
+type syslogSender interface {
+	Send(severity int, msg string) error
+	Close() error
+}
+
+// syslogStore is the result store behind --syslog: one message per result,
+// the JSON record as in --output json, masked like the other outputs
+type syslogStore struct {
+	network  string
+	addr     string
+	facility int
+	tag      string
+	secrets  []string
+	hosts    *hostRedactor
+}
+
+// newSyslogStore validates the --syslog flags. An empty address means no
+// store; the caller fills in what to mask once secrets are loaded.
+func newSyslogStore(addr, network, facility, tag string) (*syslogStore, error) {
+	if addr == "" {
+		return nil, nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid --syslog %q: want host:port", addr)
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("invalid --syslog-proto %q: want udp or tcp", network)
+	}
+	code, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return nil, fmt.Errorf("invalid --syslog-facility %q (valid: %s)", facility, strings.Join(syslogFacilityNames(), ", "))
+	}
+	if tag == "" {
+		return nil, fmt.Errorf("invalid --syslog-tag: must not be empty")
+	}
+	return &syslogStore{network: network, addr: addr, facility: code, tag: tag}, nil
+}
+
+// syslogFacilityNames lists the accepted facility names, sorted
+func syslogFacilityNames() []string {
+	names := make([]string, 0, len(syslogFacilities))
+	for name := range syslogFacilities {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// syslogSeverity maps a result to a severity: failures are err (warning
+// inside a maintenance window or below --min-severity), skips notice, and
+// passes info
+func syslogSeverity(r core.Result) int {
+	switch {
+	case r.Status == core.StatusPassed:
+		return syslogSevInfo
+	case r.Status == core.StatusSkipped:
+		return syslogSevNotice
+	case r.Maintenance, r.LowSeverity:
+		return syslogSevWarning
+	}
+	return syslogSevErr
+}
+
+// Save sends the run's results over one connection
+func (s *syslogStore) Save(_ context.Context, results []core.Result) error {
+	sender, err := dialSyslog(s.network, s.addr, s.facility, s.tag)
+	if err != nil {
+		return fmt.Errorf("syslog %s: %w", s.addr, err)
+	}
+	defer sender.Close()
+
+	for _, r := range results {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(s.hosts.Writer(newRedactWriter(&buf, s.secrets))).Encode(newResultRecord(r)); err != nil {
+			return err
+		}
+		if err := sender.Send(syslogSeverity(r), strings.TrimSuffix(buf.String(), "\n")); err != nil {
+			return fmt.Errorf("syslog %s: %w", s.addr, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"log/syslog"
+)
+
+// logSyslog sends through the standard library's syslog client
+type logSyslog struct {
+	w *syslog.Writer
+}
+
+func dialSyslog(network, addr string, facility int, tag string) (syslogSender, error) {
+	w, err := syslog.Dial(network, addr, syslog.Priority(facility<<3)|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &logSyslog{w: w}, nil
+}
+
+func (l *logSyslog) Send(severity int, msg string) error {
+	switch severity {
+	case syslogSevErr:
+		return l.w.Err(msg)
+	case syslogSevWarning:
+		return l.w.Warning(msg)
+	case syslogSevNotice:
+		return l.w.Notice(msg)
+	}
+	return l.w.Info(msg)
+}
+
+func (l *logSyslog) Close() error {
+	return l.w.Close()
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// netSyslog writes RFC 3164 messages over a plain connection, since
+// log/syslog isn't available on Windows. The format matches what
+// log/syslog sends to a remote server.
+type netSyslog struct {
+	conn     net.Conn
+	facility int
+	tag      string
+	hostname string
+}
+
+func dialSyslog(network, addr string, facility int, tag string) (syslogSender, error) {
+	conn, err := net.DialTimeout(network, addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	return &netSyslog{conn: conn, facility: facility, tag: tag, hostname: hostname}, nil
+}
+
+func (n *netSyslog) Send(severity int, msg string) error {
+	_, err := fmt.Fprintf(n.conn, "<%d>%s %s %s[%d]: %s\n",
+		n.facility<<3|severity, time.Now().Format(time.RFC3339), n.hostname, n.tag, os.Getpid(), msg)
+	return err
+}
+
+func (n *netSyslog) Close() error {
+	return n.conn.Close()
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// tagTally counts the results of one tag's hosts for the run summary
+type tagTally struct {
+	Passed  int
+	Failed  int
+	Errored int
+	Unknown int
+}
+
+// total is the number of checks that ran; skipped ones aren't counted
+func (t tagTally) total() int {
+	return t.Passed + t.Failed + t.Errored + t.Unknown
+}
+
+// tallyTags counts r under each of its host's tag= tags
+func tallyTags(byTag map[string]tagTally, r core.Result) {
+	for _, tag := range r.Host.Tags() {
+		t := byTag[tag]
+		switch r.Status {
+		case core.StatusPassed:
+			t.Passed++
+		case core.StatusFailed:
+			t.Failed++
+		case core.StatusErrored:
+			t.Errored++
+		case core.StatusUnknown:
+			t.Unknown++
+		default:
+			continue
+		}
+		byTag[tag] = t
+	}
+}
+
+// tagSummaryLine renders the per-tag pass counts in tag order, e.g.
+// "prod: 20/20, staging: 8/10"
+func tagSummaryLine(byTag map[string]tagTally) string {
+	tags := make([]string, 0, len(byTag))
+	for tag := range byTag {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = fmt.Sprintf("%s: %d/%d", tag, byTag[tag].Passed, byTag[tag].total())
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// ANSI sequences used by the live view
+const (
+	ansiCursorUp = "\x1b[%dA"
+	ansiClearEnd = "\x1b[J"
+	ansiReset    = "\x1b[0m"
+	ansiGreen    = "\x1b[32m"
+	ansiRed      = "\x1b[31m"
+	ansiYellow   = "\x1b[33m"
+	ansiDim      = "\x1b[90m"
+)
+
+// liveView redraws a host status table in place on a terminal
+type liveView struct {
+	out    io.Writer
+	hosts  []core.Host
+	rows   []string
+	header string
+	lines  int
+}
+
+// isTerminal reports whether f is attached to a character device
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func newLiveView(out io.Writer, hosts []core.Host) *liveView {
+	rows := make([]string, len(hosts))
+	for i := range rows {
+		rows[i] = ansiDim + "pending" + ansiReset + "\t"
+	}
+	return &liveView{out: out, hosts: hosts, rows: rows}
+}
+
+// StartRun sets the header shown above the table
+func (v *liveView) StartRun(run, of int) {
+	v.header = fmt.Sprintf("netcheck - run %d/%d", run, of)
+	v.draw()
+}
+
+// Checking marks a host as in progress, keeping its last latency
+func (v *liveView) Checking(i int) {
+	_, latency, _ := strings.Cut(v.rows[i], "\t")
+	v.rows[i] = ansiYellow + "checking" + ansiReset + "\t" + latency
+	v.draw()
+}
+
+// Update records a host's latest result
+func (v *liveView) Update(i int, r core.Result) {
+	color := ansiRed
+	switch r.Status {
+	case core.StatusPassed:
+		color = ansiGreen
+	case core.StatusUnknown, core.StatusSkipped:
+		color = ansiDim
+	}
+	v.rows[i] = fmt.Sprintf("%s%s%s\t%.1fms", color, r.Status, ansiReset, durationMs(r.Duration))
+	v.draw()
+}
+
+// draw moves back over the previous frame and writes the current one
+func (v *liveView) draw() {
+	var b strings.Builder
+	if v.lines > 0 {
+		fmt.Fprintf(&b, ansiCursorUp, v.lines)
+	}
+	b.WriteString("\r" + ansiClearEnd)
+	b.WriteString(v.header + "\n\n")
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tTYPE\tSTATUS\tLATENCY")
+	for i, host := range v.hosts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", host.DisplayName(), host.CheckType, v.rows[i])
+	}
+	tw.Flush()
+
+	v.lines = strings.Count(b.String(), "\n")
+	io.WriteString(v.out, b.String())
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"nexus-sds.com/netcheck/pkg/core"
+)
+
+// validateWait checks the --wait-for-healthy flags
+func validateWait() error {
+	if !waitHealthy {
+		return nil
+	}
+	if waitTimeout <= 0 {
+		return fmt.Errorf("invalid --wait-timeout %s: must be positive", waitTimeout)
+	}
+	if waitInterval < 0 {
+		return fmt.Errorf("invalid --wait-interval %s: must not be negative", waitInterval)
+	}
+	if repeatCount > 1 || probeMode || tuiMode {
+		return fmt.Errorf("--wait-for-healthy can't be combined with --repeat, --probe, or --tui")
+	}
+	return nil
+}
+
+// healthyRun reports whether every check in a run passed. Disabled hosts
+// and unknown check types skipped by --ignore-unknown don't count against
+// it, nor do failures below --min-severity; any other skip, or a failure
+// inside a maintenance window, does.
+func healthyRun(results []core.Result) bool {
+	for _, r := range results {
+		if r.Passed() || r.LowSeverity {
+			continue
+		}
+		if r.Status == core.StatusSkipped && (r.SkipReason == skipDisabled || r.SkipReason == skipUnknownType) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// waitForHealthy re-runs the config every --wait-interval until a run is
+// healthy or the next attempt would start after --wait-timeout. An attempt
+// already under way finishes. It returns the last attempt's results, the
+// number of attempts, and whether it was healthy.
+func waitForHealthy(runOnce func(stream bool) []core.Result) ([]core.Result, int, bool) {
+	deadline := time.Now().Add(waitTimeout)
+	for attempt := 1; ; attempt++ {
+		log.Info().Int("attempt", attempt).Msg("waiting for healthy: starting attempt")
+		results := runOnce(false)
+		summary := summarize(results)
+		healthy := healthyRun(results)
+		event := log.Info()
+		if !healthy {
+			event = log.Warn()
+		}
+		event.Int("attempt", attempt).Int("passed", summary.Passed).Strs("failedHosts", summary.FailedHosts).
+			Bool("healthy", healthy).Dur("remaining", time.Until(deadline).Round(time.Second)).Msg("waiting for healthy: attempt finished")
+		if healthy || !time.Now().Add(waitInterval).Before(deadline) {
+			return results, attempt, healthy
+		}
+		time.Sleep(waitInterval)
+	}
+}
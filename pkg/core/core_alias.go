@@ -0,0 +1,35 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckTypeAliases maps alternative codes accepted on input to canonical
+// check type codes. Results, logs, and JSON always use the canonical code.
+var CheckTypeAliases = map[string]string{
+	"PING": "ICMP",
+	"GET":  "HTTP",
+}
+
+// CanonicalCheckType upper-cases code and resolves it through the alias map
+func CanonicalCheckType(code string) string {
+	code = strings.ToUpper(code)
+	if canonical, ok := CheckTypeAliases[code]; ok {
+		return canonical
+	}
+	return code
+}
+
+// AddCheckTypeAlias registers alias for an existing check type code
+func AddCheckTypeAlias(alias, code string) error {
+	alias, code = strings.ToUpper(alias), strings.ToUpper(code)
+	if _, ok := CheckTypes[code]; !ok {
+		return fmt.Errorf("alias %s: unknown check type %s", alias, code)
+	}
+	if _, ok := CheckTypes[alias]; ok {
+		return fmt.Errorf("alias %s: conflicts with an existing check type", alias)
+	}
+	CheckTypeAliases[alias] = code
+	return nil
+}
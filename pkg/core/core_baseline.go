@@ -0,0 +1,115 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// baselineHashLength is how many hex characters of a body hash are shown
+const baselineHashLength = 12
+
+// baselineMode parses the diff= token: "true" fails a check whose body
+// differs from its baseline, "warn" only records the change
+func baselineMode(host Host) (mode string, enabled bool, err error) {
+	switch v := host.Tokens.Get("diff"); v {
+	case "", "false":
+		return "", false, nil
+	case "true", "warn":
+		return v, true, nil
+	default:
+		return "", false, fmt.Errorf("invalid diff %q: want true, warn, or false", v)
+	}
+}
+
+// baselinePath is where a check's baseline body lives: one file per check
+// ID, so editing the label or timing tokens keeps the baseline
+func baselinePath(dir string, host Host) string {
+	return filepath.Join(dir, host.ID()+".body")
+}
+
+// checkBaseline enforces the diff= token on HTTP and HTPS checks: the
+// response body is compared with the one stored under --baseline-dir. The
+// body is replayed into resp.Body for evaluateResponse. The first run (or
+// --update-baseline) stores the current body instead. A changed body fails
+// the check, or with diff=warn passes with a baselineChanged detail.
+func checkBaseline(ctx context.Context, host Host, resp *http.Response, opts *Options) error {
+	mode, enabled, err := baselineMode(host)
+	if err != nil || !enabled {
+		return err
+	}
+	if opts == nil || opts.BaselineDir == "" {
+		return fmt.Errorf("diff=%s needs --baseline-dir", mode)
+	}
+	if !statusAccepted(resp.StatusCode) {
+		// evaluateResponse fails it; don't store or compare an error page
+		return nil
+	}
+	body, err := bufferBody(host, resp, "baseline comparison")
+	if err != nil {
+		return err
+	}
+	current := bodyHash(body)
+	SetDetail(ctx, "bodyHash", current)
+
+	path := baselinePath(opts.BaselineDir, host)
+	stored, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) || (err == nil && opts.UpdateBaseline):
+		if err := writeBaseline(path, body); err != nil {
+			return err
+		}
+		SetDetail(ctx, "baselineUpdated", true)
+		return nil
+	case err != nil:
+		return fmt.Errorf("read baseline: %w", err)
+	}
+
+	if bytes.Equal(stored, body) {
+		return nil
+	}
+	if mode == "warn" {
+		SetDetail(ctx, "baselineChanged", true)
+		return nil
+	}
+	return fmt.Errorf("response differs from baseline %s (sha256 %s, %d bytes; now %s, %d bytes)",
+		path, bodyHash(stored), len(stored), current, len(body))
+}
+
+// bodyHash is the shortened sha256 of a body, as shown in messages
+func bodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])[:baselineHashLength]
+}
+
+// writeBaseline replaces the baseline file via a temporary file and rename,
+// so a concurrent run never reads a partial body
+func writeBaseline(path string, body []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create baseline dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write baseline: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write baseline: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write baseline: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write baseline: %w", err)
+	}
+	return nil
+}
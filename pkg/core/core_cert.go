@@ -0,0 +1,210 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// OCSP stapling modes for the ocsp= token
+const (
+	ocspCheck   = "check"
+	ocspRequire = "require"
+)
+
+// Session resumption modes for the resume= token
+const (
+	resumeRequire = "require"
+	resumeForbid  = "forbid"
+)
+
+// sessionTicketWait is how long a resume= probe reads after the first
+// handshake for TLS 1.3 session tickets
+const sessionTicketWait = 250 * time.Millisecond
+
+// tlsHandshake connects to addr (through the proxy dialer or resolver when
+// set) and completes a verified TLS handshake
+func tlsHandshake(ctx context.Context, addr string, conf *tls.Config, opts *Options) (*tls.Conn, error) {
+	conn, err := opts.dialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	tlsConn := tls.Client(conn, conf)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, describeTLSError(err)
+	}
+	return tlsConn, nil
+}
+
+// CertCheck verifies the TLS certificate served at host[:port] (443 by
+// default). It fails when the chain doesn't verify, when the certificate
+// expires within mindays=, when its key or signature is weaker than minkey=
+// or sigalg= allow, or when a stapled OCSP response isn't "good".
+func CertCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	timeout, err := opts.timeoutFor(host, defaultHTTPTimeout)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := hostAddr(host.HostName, "CERT")
+	serverName, _, _ := net.SplitHostPort(addr)
+
+	tlsConf, err := tlsConfigFor(host, opts)
+	if err != nil {
+		return false, err
+	}
+	tlsConf.ServerName = serverName
+
+	conn, err := tlsHandshake(ctx, addr, tlsConf, opts)
+	if err != nil {
+		return false, err
+	}
+	// Everything below inspects the handshake state, so the connection can
+	// go before resume= opens its own
+	state := conn.ConnectionState()
+	conn.Close()
+	leaf := state.PeerCertificates[0]
+	daysLeft := int(time.Until(leaf.NotAfter).Hours() / 24)
+	SetDetail(ctx, "subject", leaf.Subject.CommonName)
+	SetDetail(ctx, "issuer", leaf.Issuer.CommonName)
+	SetDetail(ctx, "notAfter", leaf.NotAfter.UTC().Format(time.RFC3339))
+	SetDetail(ctx, "daysLeft", daysLeft)
+
+	if v := host.Tokens.Get("mindays"); v != "" {
+		minDays, err := strconv.Atoi(v)
+		if err != nil || minDays < 0 {
+			return false, fmt.Errorf("invalid mindays %q", v)
+		}
+		if daysLeft < minDays {
+			return false, fmt.Errorf("certificate expires in %d days (%s), under mindays %d", daysLeft, leaf.NotAfter.UTC().Format(time.DateOnly), minDays)
+		}
+	}
+
+	if err := checkCertStrength(ctx, host, state.PeerCertificates); err != nil {
+		return false, err
+	}
+	if err := checkOCSPStaple(ctx, host, state); err != nil {
+		return false, err
+	}
+	if err := checkResumption(ctx, host, addr, tlsConf, opts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkResumption enforces resume=: it handshakes twice with a shared
+// session cache and fails when the second handshake resumes the session
+// under resume=forbid, or doesn't under resume=require. The outcome is
+// reported in the "resumed" detail.
+func checkResumption(ctx context.Context, host Host, addr string, conf *tls.Config, opts *Options) error {
+	mode := host.Tokens.Get("resume")
+	if mode == "" {
+		return nil
+	}
+	if mode != resumeRequire && mode != resumeForbid {
+		return fmt.Errorf("invalid resume %q (want require or forbid)", mode)
+	}
+
+	conf = conf.Clone()
+	conf.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	if conf.ServerName == "" {
+		conf.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+
+	first, err := tlsHandshake(ctx, addr, conf, opts)
+	if err != nil {
+		return fmt.Errorf("resume: first handshake: %w", err)
+	}
+	// TLS 1.3 servers send tickets after the handshake and the client only
+	// stores them while reading, so wait briefly for any to arrive
+	if first.ConnectionState().Version >= tls.VersionTLS13 {
+		first.SetReadDeadline(time.Now().Add(sessionTicketWait))
+		first.Read(make([]byte, 1))
+	}
+	first.Close()
+
+	second, err := tlsHandshake(ctx, addr, conf, opts)
+	if err != nil {
+		return fmt.Errorf("resume: second handshake: %w", err)
+	}
+	resumed := second.ConnectionState().DidResume
+	second.Close()
+	SetDetail(ctx, "resumed", resumed)
+
+	if mode == resumeRequire && !resumed {
+		return fmt.Errorf("TLS session was not resumed (resume=require)")
+	}
+	if mode == resumeForbid && resumed {
+		return fmt.Errorf("TLS session was resumed (resume=forbid)")
+	}
+	return nil
+}
+
+// checkOCSPStaple validates the stapled OCSP response, if any. With
+// ocsp=require a missing staple fails the check; a revoked or unknown status
+// fails whenever a staple is present.
+func checkOCSPStaple(ctx context.Context, host Host, state tls.ConnectionState) error {
+	mode := strings.ToLower(host.Tokens.Get("ocsp"))
+	if mode != "" && mode != ocspCheck && mode != ocspRequire {
+		return fmt.Errorf("invalid ocsp %q (valid: check, require)", mode)
+	}
+
+	if len(state.OCSPResponse) == 0 {
+		SetDetail(ctx, "ocsp", "absent")
+		if mode == ocspRequire {
+			return fmt.Errorf("OCSP staple required but the server didn't send one")
+		}
+		return nil
+	}
+
+	leaf := state.PeerCertificates[0]
+	issuer := ocspIssuer(state)
+	if issuer == nil {
+		return fmt.Errorf("OCSP staple present but the issuer certificate is unavailable")
+	}
+	resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer)
+	if err != nil {
+		return fmt.Errorf("invalid OCSP staple: %w", err)
+	}
+
+	if !resp.NextUpdate.IsZero() {
+		SetDetail(ctx, "ocspNextUpdate", resp.NextUpdate.UTC().Format(time.RFC3339))
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		SetDetail(ctx, "ocsp", "good")
+		return nil
+	case ocsp.Revoked:
+		SetDetail(ctx, "ocsp", "revoked")
+		return fmt.Errorf("certificate revoked at %s (OCSP)", resp.RevokedAt.UTC().Format(time.RFC3339))
+	default:
+		SetDetail(ctx, "ocsp", "unknown")
+		return fmt.Errorf("OCSP staple reports unknown certificate status")
+	}
+}
+
+// ocspIssuer returns the certificate that issued the leaf, preferring the
+// verified chain
+func ocspIssuer(state tls.ConnectionState) *x509.Certificate {
+	if len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 1 {
+		return state.VerifiedChains[0][1]
+	}
+	if len(state.PeerCertificates) > 1 {
+		return state.PeerCertificates[1]
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// certKeyBits returns the size of a certificate's public key: the modulus
+// for RSA, the curve for ECDSA, or 0 when unknown
+func certKeyBits(cert *x509.Certificate) int {
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return pub.N.BitLen()
+	case *ecdsa.PublicKey:
+		return pub.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
+// sigAlgParts splits a signature algorithm name into the lowercase parts
+// sigalg= matches: "SHA1-RSA" is "sha1" and "rsa"
+func sigAlgParts(alg x509.SignatureAlgorithm) []string {
+	return strings.Split(strings.ToLower(alg.String()), "-")
+}
+
+// sigAlgNames lists the names sigalg= accepts: each full algorithm name and
+// each of its parts, sorted
+func sigAlgNames() []string {
+	var names []string
+	for alg := x509.MD5WithRSA; alg <= x509.PureEd25519; alg++ {
+		names = append(names, strings.ToLower(alg.String()))
+		names = append(names, sigAlgParts(alg)...)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// sigAlgPolicy is a parsed sigalg= token. Names prefixed with "!" are
+// forbidden; when any plain names are given, the algorithm must match one.
+type sigAlgPolicy struct {
+	allow, deny []string
+}
+
+func parseSigAlgPolicy(spec string) (*sigAlgPolicy, error) {
+	valid := sigAlgNames()
+	policy := &sigAlgPolicy{}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		deny := strings.HasPrefix(name, "!")
+		name = strings.TrimPrefix(name, "!")
+		if !slices.Contains(valid, name) {
+			return nil, fmt.Errorf("invalid sigalg name %q (valid: %s)", name, strings.Join(valid, ", "))
+		}
+		if deny {
+			policy.deny = append(policy.deny, name)
+		} else {
+			policy.allow = append(policy.allow, name)
+		}
+	}
+	return policy, nil
+}
+
+// sigAlgMatches reports whether name is alg's full name or one of its parts
+func sigAlgMatches(alg x509.SignatureAlgorithm, name string) bool {
+	return name == strings.ToLower(alg.String()) || slices.Contains(sigAlgParts(alg), name)
+}
+
+// violation describes how alg breaks the policy, or returns ""
+func (p *sigAlgPolicy) violation(alg x509.SignatureAlgorithm) string {
+	for _, name := range p.deny {
+		if sigAlgMatches(alg, name) {
+			return fmt.Sprintf("signed with %s (sigalg forbids %s)", alg, name)
+		}
+	}
+	if len(p.allow) == 0 {
+		return ""
+	}
+	for _, name := range p.allow {
+		if sigAlgMatches(alg, name) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("signed with %s (sigalg allows %s)", alg, strings.Join(p.allow, ", "))
+}
+
+// checkCertStrength enforces minkey= and sigalg= on CERT checks. The leaf's
+// key type, key size, and signature algorithm are always reported as
+// details. minkey= is the smallest RSA modulus in bits; ECDSA and Ed25519
+// keys are on a different scale and aren't compared. With chain=true the
+// intermediates the server sends are held to the same policy.
+func checkCertStrength(ctx context.Context, host Host, certs []*x509.Certificate) error {
+	leaf := certs[0]
+	SetDetail(ctx, "keyType", leaf.PublicKeyAlgorithm.String())
+	SetDetail(ctx, "keyBits", certKeyBits(leaf))
+	SetDetail(ctx, "sigAlg", leaf.SignatureAlgorithm.String())
+
+	minKey := 0
+	if v := host.Tokens.Get("minkey"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid minkey %q: want bits", v)
+		}
+		minKey = n
+	}
+	var policy *sigAlgPolicy
+	if v := host.Tokens.Get("sigalg"); v != "" {
+		var err error
+		if policy, err = parseSigAlgPolicy(v); err != nil {
+			return err
+		}
+	}
+	checkChain := false
+	if v := host.Tokens.Get("chain"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid chain %q: want true or false", v)
+		}
+		checkChain = b
+	}
+	if !checkChain {
+		certs = certs[:1]
+	}
+
+	var problems []string
+	for i, cert := range certs {
+		name := "leaf"
+		if i > 0 {
+			name = fmt.Sprintf("intermediate %q", cert.Subject.CommonName)
+		}
+		if _, isRSA := cert.PublicKey.(*rsa.PublicKey); isRSA && minKey > 0 {
+			if bits := certKeyBits(cert); bits < minKey {
+				problems = append(problems, fmt.Sprintf("%s has a %d-bit RSA key, under minkey %d", name, bits, minKey))
+			}
+		}
+		if policy != nil {
+			if v := policy.violation(cert.SignatureAlgorithm); v != "" {
+				problems = append(problems, name+" "+v)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("weak certificate: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package core
+
+import "sync"
+
+// SchemeCache remembers the scheme ("http" or "https") that answered each
+// COMB host, keyed by check ID, so later runs in the same process (--repeat,
+// --wait-for-healthy) probe only that scheme. A nil cache remembers nothing.
+type SchemeCache struct {
+	mu      sync.Mutex
+	schemes map[string]string
+}
+
+// NewSchemeCache returns an empty scheme cache
+func NewSchemeCache() *SchemeCache {
+	return &SchemeCache{schemes: map[string]string{}}
+}
+
+// get returns the remembered scheme for id, or ""
+func (c *SchemeCache) get(id string) string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.schemes[id]
+}
+
+// set remembers the scheme that answered for id
+func (c *SchemeCache) set(id, scheme string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schemes[id] = scheme
+}
+
+// forget drops id's scheme after it stopped answering
+func (c *SchemeCache) forget(id string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.schemes, id)
+}
+
+// combSchemes returns the --comb-sticky cache, nil when it's off
+func (o *Options) combSchemes() *SchemeCache {
+	if o == nil {
+		return nil
+	}
+	return o.CombSchemes
+}
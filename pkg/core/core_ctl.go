@@ -1,28 +1,76 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
-
-	lua "github.com/yuin/gopher-lua"
 )
 
 type Host struct {
 	HostName  string
 	CheckType string
+	Label     string
+	Tokens    Tokens
+
+	// Disabled hosts stay in the config and plan but aren't checked
+	Disabled bool
+
+	// DualStack is the name of the host an ipv=both (or --dual-stack) check
+	// was split from; its v4 and v6 halves share it. Empty otherwise.
+	DualStack string
+
+	// Source is the text config the host was parsed from, so it can be
+	// written back out; nil for YAML and JSON configs. Hosts expanded from
+	// one combined line share it.
+	Source *HostSource
+}
+
+// HostSource is a host's line in a text config as written, with the
+// comment lines directly above it and the @defaults lines that applied to it
+type HostSource struct {
+	Line     string
+	Comments []string
+	Defaults []string
 }
 
-var CheckTypes = map[string]func(host Host) (bool, error){
+// Expanded returns a copy of the host with ${VAR} references in the hostname
+// and token values resolved against the environment. Callers expand just
+// before running a check so secrets never end up in logged host fields.
+func (h Host) Expanded() Host {
+	h.HostName = ExpandVars(h.HostName)
+	h.Tokens = h.Tokens.Expanded()
+	return h
+}
+
+// DisplayName returns the friendly label for the host, falling back to the
+// hostname when no label was configured
+func (h Host) DisplayName() string {
+	if h.Label != "" {
+		return h.Label
+	}
+	return h.HostName
+}
+
+var CheckTypes = map[string]CheckFunc{
 	"ICMP": IcmpPing,
 	"HTTP": HttpCheck,
 	"HTPS": HttpsCheck,
 	"COMB": ComboHttpCheck,
+	"MULT": MultiHttpCheck,
+	"DOH":  DoHCheck,
+	"DOT":  DoTCheck,
+	"CERT": CertCheck,
+	"NTP":  NTPCheck,
+	"TCP":  TcpCheck,
+	"SVC":  ServiceCheck,
+	"JRPC": JSONRPCCheck,
 	"LUA":  LuaScript,
 	"PY":   PythonScript,
 	"PS":   PowerShellScript,
@@ -33,116 +81,427 @@ var CheckTypeNames = map[string]string{
 	"HTTP": "HTTP Check",
 	"HTPS": "HTTPS Check",
 	"COMB": "Combo HTTP/HTTPS Check",
+	"MULT": "Multi-URL HTTP Check",
+	"DOH":  "DNS over HTTPS Check",
+	"DOT":  "DNS over TLS Check",
+	"CERT": "TLS Certificate Check",
+	"NTP":  "NTP Server Check",
+	"TCP":  "TCP Connect Check",
+	"SVC":  "Local Service Check",
+	"JRPC": "JSON-RPC Check",
 	"LUA":  "Lua Script",
 	"PY":   "Python Script",
 	"PS":   "PowerShell Script",
 }
 
-func IcmpPing(host Host) (bool, error) {
+// IcmpPing pings the host from a native ICMP socket, or with the system
+// ping command when no socket can be opened (see Options.ICMPMode). With
+// count= it sends that many packets and judges the loss against maxloss=,
+// reporting loss and average round trip as details.
+func IcmpPing(ctx context.Context, host Host, opts *Options) (bool, error) {
+	if host.Tokens.Has("via") {
+		return viaCheck(ctx, host, opts)
+	}
+	// ICMP isn't TCP, so it can't follow the other checks through a proxy
+	if opts.proxied() {
+		return false, fmt.Errorf("ICMP: %w", ErrProxyUnsupported)
+	}
+
+	timeout, err := opts.timeoutFor(host, defaultPingTimeout)
+	if err != nil {
+		return false, err
+	}
+	count, err := pingCount(host)
+	if err != nil {
+		return false, err
+	}
+	maxLoss, hasMaxLoss, err := pingMaxLoss(host)
+	if err != nil {
+		return false, err
+	}
+	multi := host.Tokens.Has("count") || hasMaxLoss
+
+	// Backstop in case ping ignores its own wait time; packets go out a
+	// second apart
+	ctx, cancel := context.WithTimeout(ctx, timeout+time.Duration(count)*time.Second)
+	defer cancel()
+
+	// A native ICMP socket avoids starting a process per check
+	if modes := opts.icmpSocketModes(); len(modes) > 0 {
+		stats, ran, err := nativePing(ctx, host, opts, modes, count, timeout)
+		if ran {
+			if err != nil {
+				return false, err
+			}
+			if !multi {
+				if stats.received == 0 {
+					return false, fmt.Errorf("no echo reply within %s", timeout)
+				}
+				return true, nil
+			}
+			return judgePingStats(ctx, stats, hasMaxLoss, maxLoss)
+		}
+	}
+	SetDetail(ctx, "icmpMode", ICMPModeExec)
+
 	// Use system ping command to avoid needing raw socket permissions
+	n := strconv.Itoa(count)
+	pingBin := "ping"
+	if opts != nil && opts.PingBin != "" {
+		pingBin = opts.PingBin
+	}
+	// ping resolves the name itself unless a resolver or ipv= is set
+	target, err := opts.resolveHost(ctx, host.HostName)
+	if err != nil {
+		return false, err
+	}
 	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		// Windows: ping -n 1 -w 2000 host
-		cmd = exec.Command("ping", "-n", "1", "-w", "2000", host.HostName)
-	} else {
-		// Unix/Linux/macOS: ping -c 1 -W 2 host
-		cmd = exec.Command("ping", "-c", "1", "-W", "2", host.HostName)
+	switch runtime.GOOS {
+	case "windows":
+		// Windows: ping -n <count> -w <milliseconds> host
+		cmd = exec.CommandContext(ctx, pingBin, "-n", n, "-w", strconv.FormatInt(timeout.Milliseconds(), 10), target)
+	case "darwin":
+		if familyFrom(ctx) == FamilyV6 && (opts == nil || opts.PingBin == "") {
+			// macOS pings IPv6 with a separate ping6, which has no -W
+			cmd = exec.CommandContext(ctx, "ping6", "-c", n, target)
+			break
+		}
+		// macOS: ping -c <count> -W <milliseconds> host
+		cmd = exec.CommandContext(ctx, pingBin, "-c", n, "-W", strconv.FormatInt(timeout.Milliseconds(), 10), target)
+	default:
+		// Unix/Linux: ping -c <count> -W <seconds> host
+		cmd = exec.CommandContext(ctx, pingBin, "-c", n, "-W", strconv.Itoa(pingWaitSeconds(timeout)), target)
 	}
 
-	err := cmd.Run()
+	release, err := opts.acquireProcess(ctx)
 	if err != nil {
 		return false, err
 	}
+	defer release()
+	if !multi {
+		err = cmd.Run()
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	output, runErr := cmd.Output()
+	return judgePingOutput(ctx, string(output), runErr, hasMaxLoss, maxLoss)
+}
+
+// judgePingOutput judges a count=/maxloss= ping by its summary, reporting
+// loss and average round trip as details. ping exits non-zero on partial
+// loss on some platforms, so runErr only counts when there's no summary.
+func judgePingOutput(ctx context.Context, output string, runErr error, hasMaxLoss bool, maxLoss float64) (bool, error) {
+	stats, ok := parsePingSummary(output)
+	if !ok {
+		if runErr != nil {
+			return false, runErr
+		}
+		return false, fmt.Errorf("unrecognised ping output")
+	}
+	return judgePingStats(ctx, stats, hasMaxLoss, maxLoss)
+}
+
+// judgePingStats judges a count=/maxloss= run's packet counts, reporting
+// loss and average round trip as details
+func judgePingStats(ctx context.Context, stats pingStats, hasMaxLoss bool, maxLoss float64) (bool, error) {
+	loss := stats.lossPercent()
+	SetDetail(ctx, "lossPct", loss)
+	if stats.received > 0 {
+		SetDetail(ctx, "avgRttMs", stats.avgRTT)
+	}
+	if stats.received == 0 {
+		return false, fmt.Errorf("no replies to %d packets", stats.sent)
+	}
+	if hasMaxLoss && loss > maxLoss {
+		return false, fmt.Errorf("packet loss %.0f%% (%d/%d received) exceeds maxloss %g%%", loss, stats.received, stats.sent, maxLoss)
+	}
 	return true, nil
 }
 
-func HttpCheck(host Host) (bool, error) {
+// pingWaitSeconds converts a timeout to the whole seconds Linux ping -W takes
+func pingWaitSeconds(timeout time.Duration) int {
+	seconds := int((timeout + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+func HttpCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	if host.Tokens.Has("via") {
+		return viaCheck(ctx, host, opts)
+	}
+	timeout, err := opts.timeoutFor(host, defaultHTTPTimeout)
+	if err != nil {
+		return false, err
+	}
+
 	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+	client, err := newCheckClient(host, nil, timeout, opts)
+	if err != nil {
+		return false, err
 	}
+	defer client.CloseIdleConnections()
 
-	// Build URL - always use port 80
-	url := fmt.Sprintf("http://%s:80", host.HostName)
+	// Build URL - port 80 unless the host or --default-port says otherwise
+	url := "http://" + hostAddr(host.HostName, "HTTP")
 
-	// Make GET request
-	resp, err := client.Get(url)
+	// Make the request (GET unless method= or body= say otherwise), timing
+	// each phase
+	ctx, trace := withPhaseTrace(ctx)
+	req, err := newCheckRequest(ctx, host, checkMethod(host), url)
 	if err != nil {
 		return false, err
 	}
+	resp, err := doCheckRequest(ctx, client, host, req)
+	if err != nil {
+		return false, describeTimeout(ctx, err, trace, timeout)
+	}
 	defer resp.Body.Close()
 
-	// Check if status code is 200 OK or 404 Not Found
-	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound {
-		return true, nil
+	// Check the framing, compare against the stored baseline, the expected
+	// version, and any array lengths, then check status code and the other
+	// body assertions
+	if err := checkTransfer(ctx, host, resp); err != nil {
+		return false, err
+	}
+	if err := checkBaseline(ctx, host, resp, opts); err != nil {
+		return false, err
+	}
+	if err := checkVersion(ctx, host, resp); err != nil {
+		return false, err
+	}
+	if err := checkJSONLen(ctx, host, resp); err != nil {
+		return false, err
+	}
+	if err := evaluateResponse(host, resp); err != nil {
+		return false, err
+	}
+
+	// Record the phase breakdown and enforce any per-phase limits
+	if err := trace.report(ctx, host); err != nil {
+		return false, err
 	}
 
-	return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	return true, nil
 }
 
-func HttpsCheck(host Host) (bool, error) {
+func HttpsCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	if host.Tokens.Has("via") {
+		return viaCheck(ctx, host, opts)
+	}
+	timeout, err := opts.timeoutFor(host, defaultHTTPTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	// Build TLS config from per-host tokens (client cert, custom CA)
+	tlsConf, err := tlsConfigFor(host, opts)
+	if err != nil {
+		return false, err
+	}
+
 	// Create HTTPS client with timeout
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+	client, err := newCheckClient(host, tlsConf, timeout, opts)
+	if err != nil {
+		return false, err
 	}
+	defer client.CloseIdleConnections()
 
-	// Build URL - always use port 443
-	url := fmt.Sprintf("https://%s:443", host.HostName)
+	// Build URL - port 443 unless the host or --default-port says otherwise
+	url := "https://" + hostAddr(host.HostName, "HTPS")
 
-	// Make GET request
-	resp, err := client.Get(url)
+	// Make the request (GET unless method= or body= say otherwise), timing
+	// each phase
+	ctx, trace := withPhaseTrace(ctx)
+	req, err := newCheckRequest(ctx, host, checkMethod(host), url)
 	if err != nil {
 		return false, err
 	}
+	resp, err := doCheckRequest(ctx, client, host, req)
+	if err != nil {
+		return false, describeTLSError(describeTimeout(ctx, err, trace, timeout))
+	}
 	defer resp.Body.Close()
 
-	// Check if status code is 200 OK or 404 Not Found
-	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound {
-		return true, nil
+	// Check the framing, compare against the stored baseline, the expected
+	// version, and any array lengths, then check status code and the other
+	// body assertions
+	if err := checkTransfer(ctx, host, resp); err != nil {
+		return false, err
+	}
+	if err := checkBaseline(ctx, host, resp, opts); err != nil {
+		return false, err
+	}
+	if err := checkVersion(ctx, host, resp); err != nil {
+		return false, err
+	}
+	if err := checkJSONLen(ctx, host, resp); err != nil {
+		return false, err
+	}
+	if err := evaluateResponse(host, resp); err != nil {
+		return false, err
+	}
+
+	// Record the phase breakdown and enforce any per-phase limits
+	if err := trace.report(ctx, host); err != nil {
+		return false, err
+	}
+
+	// secheaders= asserts security headers on the final response
+	if err := checkSecurityHeaders(ctx, host, resp); err != nil {
+		return false, err
 	}
 
-	return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	// resume= probes session resumption with two fresh handshakes
+	if host.Tokens.Has("resume") {
+		ctx, cancel := withTimeout(ctx, timeout)
+		defer cancel()
+		if err := checkResumption(ctx, host, hostAddr(host.HostName, "HTPS"), tlsConf, opts); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
 }
 
-func ComboHttpCheck(host Host) (bool, error) {
+func ComboHttpCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	timeout, err := opts.timeoutFor(host, defaultHTTPTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	// Build TLS config from per-host tokens (client cert, custom CA)
+	tlsConf, err := tlsConfigFor(host, opts)
+	if err != nil {
+		return false, err
+	}
+
 	// Try both HTTP and HTTPS - return true if either succeeds
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+	client, err := newCheckClient(host, tlsConf, timeout, opts)
+	if err != nil {
+		return false, err
 	}
+	defer client.CloseIdleConnections()
 
-	var httpErr, httpsErr error
+	// method=HEAD checks reachability without downloading bodies
+	method := checkMethod(host)
 
-	// Try HTTP on port 80
-	httpUrl := fmt.Sprintf("http://%s:80", host.HostName)
-	httpResp, err := client.Get(httpUrl)
-	if err == nil {
-		defer httpResp.Body.Close()
-		if httpResp.StatusCode == http.StatusOK || httpResp.StatusCode == http.StatusNotFound {
+	// With --comb-sticky, probe only the scheme that answered last time;
+	// when it stops answering, forget it and probe both again
+	if sticky := opts.combSchemes().get(host.ID()); sticky != "" {
+		if err := comboProbe(ctx, host, client, method, comboURL(host, sticky)); err == nil {
+			SetDetail(ctx, "scheme", sticky)
 			return true, nil
 		}
-		httpErr = fmt.Errorf("http unexpected status code: %d", httpResp.StatusCode)
-	} else {
-		httpErr = fmt.Errorf("http error: %w", err)
+		opts.combSchemes().forget(host.ID())
+		SetDetail(ctx, "stickySchemeInvalidated", sticky)
 	}
 
-	// Try HTTPS on port 443
-	httpsUrl := fmt.Sprintf("https://%s:443", host.HostName)
-	httpsResp, err := client.Get(httpsUrl)
-	if err == nil {
-		defer httpsResp.Body.Close()
-		if httpsResp.StatusCode == http.StatusOK || httpsResp.StatusCode == http.StatusNotFound {
-			return true, nil
+	// fast=true probes both schemes at once and stops at the first success
+	if host.Tokens.Get("fast") == "true" {
+		scheme, err := comboFast(ctx, host, client, method)
+		if err != nil {
+			return false, err
 		}
-		httpsErr = fmt.Errorf("https unexpected status code: %d", httpsResp.StatusCode)
-	} else {
-		httpsErr = fmt.Errorf("https error: %w", err)
+		comboAnswered(ctx, host, opts, scheme)
+		return true, nil
+	}
+
+	var httpErr, httpsErr error
+
+	// Try HTTP on the HTTP port
+	if httpErr = comboProbe(ctx, host, client, method, comboURL(host, "http")); httpErr == nil {
+		comboAnswered(ctx, host, opts, "http")
+		return true, nil
+	}
+	httpErr = fmt.Errorf("http %w", httpErr)
+
+	// Try HTTPS on the HTPS port
+	if httpsErr = comboProbe(ctx, host, client, method, comboURL(host, "https")); httpsErr == nil {
+		comboAnswered(ctx, host, opts, "https")
+		return true, nil
 	}
+	httpsErr = fmt.Errorf("https %w", httpsErr)
 
 	// Both failed
-	return false, fmt.Errorf("both checks failed - %v; %v", httpErr, httpsErr)
+	return false, fmt.Errorf("both checks failed - %w; %w", httpErr, httpsErr)
+}
+
+// comboURL is a COMB host's URL for scheme, on that scheme's port
+func comboURL(host Host, scheme string) string {
+	if scheme == "https" {
+		return "https://" + hostAddr(host.HostName, "HTPS")
+	}
+	return "http://" + hostAddr(host.HostName, "HTTP")
+}
+
+// comboAnswered records the scheme that passed a COMB check, and remembers
+// it for the next run with --comb-sticky
+func comboAnswered(ctx context.Context, host Host, opts *Options, scheme string) {
+	SetDetail(ctx, "scheme", scheme)
+	opts.combSchemes().set(host.ID(), scheme)
 }
 
-func LuaScript(host Host) (bool, error) {
+// comboFast issues the HTTP and HTTPS probes concurrently and returns the
+// scheme of whichever passes first, cancelling the other request via the
+// shared context
+func comboFast(ctx context.Context, host Host, client *http.Client, method string) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		scheme string
+		err    error
+	}
+	outcomes := make(chan outcome, 2)
+	for _, scheme := range []string{"http", "https"} {
+		go func() {
+			outcomes <- outcome{scheme, comboProbe(ctx, host, client, method, comboURL(host, scheme))}
+		}()
+	}
+
+	var httpErr, httpsErr error
+	for range 2 {
+		o := <-outcomes
+		if o.err == nil {
+			return o.scheme, nil
+		}
+		if o.scheme == "http" {
+			httpErr = fmt.Errorf("http %w", o.err)
+		} else {
+			httpsErr = fmt.Errorf("https %w", o.err)
+		}
+	}
+
+	// Both failed
+	return "", fmt.Errorf("both checks failed - %w; %w", httpErr, httpsErr)
+}
+
+// comboProbe makes a single combo request and evaluates the response
+func comboProbe(ctx context.Context, host Host, client *http.Client, method, url string) error {
+	req, err := newCheckRequest(ctx, host, method, url)
+	if err != nil {
+		return fmt.Errorf("error: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error: %w", describeTLSError(err))
+	}
+	defer resp.Body.Close()
+
+	return evaluateResponse(host, resp)
+}
+
+func LuaScript(ctx context.Context, host Host, opts *Options) (bool, error) {
+	timeout, err := opts.timeoutFor(host, 0)
+	if err != nil {
+		return false, err
+	}
+
 	// Parse hostname field to extract script name and actual hostname
 	// Expected format: "scriptname.lua hostname"
 	parts := strings.Fields(host.HostName)
@@ -166,37 +525,27 @@ func LuaScript(host Host) (bool, error) {
 		return false, fmt.Errorf("script not found: %s", scriptPath)
 	}
 
-	// Create new Lua state
-	L := lua.NewState()
-	defer L.Close()
-
-	// Set hostname as global variable for the script
-	L.SetGlobal("hostname", lua.LString(actualHostname))
-
-	// Execute the Lua script
-	if err := L.DoFile(scriptPath); err != nil {
-		return false, fmt.Errorf("lua script error: %w", err)
+	env, err := scriptEnv(host)
+	if err != nil {
+		return false, err
 	}
 
-	// Get the result from the global variable 'result' set by the script
-	result := L.GetGlobal("result")
-	if result == lua.LNil {
-		return false, fmt.Errorf("lua script did not set 'result' variable")
+	// Run the script in its own Lua state, cancelled when the timeout expires
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	var data *ScriptData
+	if opts != nil {
+		data = opts.Data
 	}
+	return runLua(ctx, scriptPath, actualHostname, env, data, timeout)
+}
 
-	// Convert result to boolean
-	resultBool := lua.LVAsBool(result)
-
-	// Check if there's an error message from the script
-	errorMsg := L.GetGlobal("error_message")
-	if !resultBool && errorMsg != lua.LNil {
-		return false, fmt.Errorf("lua script failed: %s", errorMsg.String())
+func PythonScript(ctx context.Context, host Host, opts *Options) (bool, error) {
+	timeout, err := opts.timeoutFor(host, 0)
+	if err != nil {
+		return false, err
 	}
 
-	return resultBool, nil
-}
-
-func PythonScript(host Host) (bool, error) {
 	// Parse hostname field to extract script name and actual hostname
 	// Expected format: "scriptname.py hostname"
 	parts := strings.Fields(host.HostName)
@@ -206,6 +555,10 @@ func PythonScript(host Host) (bool, error) {
 
 	scriptName := parts[0]
 	actualHostname := strings.Join(parts[1:], " ")
+	exitMap, err := scriptExitMap(host)
+	if err != nil {
+		return false, err
+	}
 
 	// Ensure script name ends with .py
 	if !strings.HasSuffix(strings.ToLower(scriptName), ".py") {
@@ -220,29 +573,34 @@ func PythonScript(host Host) (bool, error) {
 		return false, fmt.Errorf("script not found: %s", scriptPath)
 	}
 
-	// Try python3 first, fall back to python
+	// Use the configured binary, else try python3 first and fall back to python
 	pythonCmd := "python3"
-	if _, err := exec.LookPath("python3"); err != nil {
+	if opts != nil && opts.PythonBin != "" {
+		pythonCmd = opts.PythonBin
+	} else if _, err := exec.LookPath("python3"); err != nil {
 		pythonCmd = "python"
 	}
 
 	// Execute the Python script with hostname as argument
-	cmd := exec.Command(pythonCmd, scriptPath, actualHostname)
-	output, err := cmd.CombinedOutput()
-
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, pythonCmd, scriptPath, actualHostname)
+	release, err := opts.acquireProcess(ctx)
 	if err != nil {
-		// Script failed - include output in error message
-		if len(output) > 0 {
-			return false, fmt.Errorf("python script failed: %s", strings.TrimSpace(string(output)))
-		}
-		return false, fmt.Errorf("python script failed: %w", err)
+		return false, err
 	}
+	defer release()
 
-	// Script succeeded
-	return true, nil
+	// Judge the exit code against map= or passcode= (default 0)
+	return runScriptCommand(ctx, cmd, host, opts, "python", timeout, exitMap)
 }
 
-func PowerShellScript(host Host) (bool, error) {
+func PowerShellScript(ctx context.Context, host Host, opts *Options) (bool, error) {
+	timeout, err := opts.timeoutFor(host, 0)
+	if err != nil {
+		return false, err
+	}
+
 	// Parse hostname field to extract script name and actual hostname
 	// Expected format: "scriptname.ps1 hostname"
 	parts := strings.Fields(host.HostName)
@@ -266,25 +624,26 @@ func PowerShellScript(host Host) (bool, error) {
 		return false, fmt.Errorf("script not found: %s", scriptPath)
 	}
 
-	// Try pwsh (PowerShell 7+) first, fall back to powershell (Windows PowerShell)
+	// Use the configured binary, else try pwsh (PowerShell 7+) first and fall
+	// back to powershell (Windows PowerShell)
 	psCmd := "pwsh"
-	if _, err := exec.LookPath("pwsh"); err != nil {
+	if opts != nil && opts.PwshBin != "" {
+		psCmd = opts.PwshBin
+	} else if _, err := exec.LookPath("pwsh"); err != nil {
 		psCmd = "powershell"
 	}
 
 	// Execute the PowerShell script with hostname as argument
 	// Use -File to execute the script and pass hostname as argument
-	cmd := exec.Command(psCmd, "-NoProfile", "-NonInteractive", "-File", scriptPath, actualHostname)
-	output, err := cmd.CombinedOutput()
-
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, psCmd, "-NoProfile", "-NonInteractive", "-File", scriptPath, actualHostname)
+	release, err := opts.acquireProcess(ctx)
 	if err != nil {
-		// Script failed - include output in error message
-		if len(output) > 0 {
-			return false, fmt.Errorf("powershell script failed: %s", strings.TrimSpace(string(output)))
-		}
-		return false, fmt.Errorf("powershell script failed: %w", err)
+		return false, err
 	}
+	defer release()
 
-	// Script succeeded
-	return true, nil
+	// Judge the exit code against passcode= (default 0)
+	return runScriptCommand(ctx, cmd, host, opts, "powershell", timeout, nil)
 }
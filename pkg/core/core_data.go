@@ -0,0 +1,90 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Environment variables that hand --data-file to PY and PS scripts
+const (
+	EnvDataFile = "NETCHECK_DATA_FILE"
+	EnvData     = "NETCHECK_DATA"
+)
+
+// maxDataEnvBytes caps the JSON passed inline in NETCHECK_DATA; a larger
+// document would hit the OS limit on one environment variable, so scripts
+// read it from NETCHECK_DATA_FILE instead
+const maxDataEnvBytes = 64 << 10
+
+// ScriptData is the --data-file document shared with script checks
+type ScriptData struct {
+	Path  string // absolute path of the file
+	Raw   []byte // compact JSON text
+	Value any    // decoded document, for the Lua data global
+}
+
+// LoadScriptData reads and validates a JSON data file for script checks
+func LoadScriptData(path string) (*ScriptData, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(content, &value); err != nil {
+		return nil, fmt.Errorf("%s isn't valid JSON: %w", path, err)
+	}
+	var raw bytes.Buffer
+	if err := json.Compact(&raw, content); err != nil {
+		return nil, err
+	}
+	return &ScriptData{Path: abs, Raw: raw.Bytes(), Value: value}, nil
+}
+
+// env returns the variables that pass the data to a script process:
+// always the file path, and the JSON itself when it's small enough
+func (d *ScriptData) env() []string {
+	if d == nil {
+		return nil
+	}
+	env := []string{EnvDataFile + "=" + d.Path}
+	if len(d.Raw) <= maxDataEnvBytes {
+		env = append(env, EnvData+"="+string(d.Raw))
+	}
+	return env
+}
+
+// luaValue converts a decoded JSON value to its Lua equivalent: objects
+// and arrays become tables (arrays 1-based), null becomes nil
+func luaValue(L *lua.LState, v any) lua.LValue {
+	switch v := v.(type) {
+	case map[string]any:
+		table := L.CreateTable(0, len(v))
+		for key, item := range v {
+			table.RawSetString(key, luaValue(L, item))
+		}
+		return table
+	case []any:
+		table := L.CreateTable(len(v), 0)
+		for i, item := range v {
+			table.RawSetInt(i+1, luaValue(L, item))
+		}
+		return table
+	case string:
+		return lua.LString(v)
+	case float64:
+		return lua.LNumber(v)
+	case bool:
+		return lua.LBool(v)
+	default:
+		return lua.LNil
+	}
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// detailsKey is the context key for the per-check detail recorder
+type detailsKey struct{}
+
+// detailRecorder collects values a check reports about its run (exit code,
+// latency breakdown, ...). Checks may record from several goroutines.
+type detailRecorder struct {
+	mu     sync.Mutex
+	values map[string]any
+}
+
+// WithDetails returns a context that collects details recorded by a check
+// via SetDetail. Call the returned function after the check to read them.
+func WithDetails(ctx context.Context) (context.Context, func() map[string]any) {
+	rec := &detailRecorder{values: map[string]any{}}
+	return context.WithValue(ctx, detailsKey{}, rec), func() map[string]any {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		return maps.Clone(rec.values)
+	}
+}
+
+// SetDetail records a detail for the running check. It is a no-op when the
+// caller didn't ask for details.
+func SetDetail(ctx context.Context, key string, value any) {
+	rec, ok := ctx.Value(detailsKey{}).(*detailRecorder)
+	if !ok {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.values[key] = value
+}
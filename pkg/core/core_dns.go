@@ -0,0 +1,241 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsMessageType is the RFC 8484 media type for DNS wire-format messages
+const dnsMessageType = "application/dns-message"
+
+// maxDNSMessage bounds a DNS message read from a DoH response
+const maxDNSMessage = 65535
+
+// defaultQueryName is looked up when neither the DoH URL nor query= names one
+const defaultQueryName = "example.com"
+
+// dnsQueryTypes maps qtype= values to DNS record types
+var dnsQueryTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"TXT":   dnsmessage.TypeTXT,
+	"SOA":   dnsmessage.TypeSOA,
+}
+
+// buildDNSQuery encodes a recursive query for name. The ID is zero as RFC
+// 8484 recommends for cache-friendly DoH requests.
+func buildDNSQuery(name string, qtype dnsmessage.Type) ([]byte, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query name %q: %w", name, err)
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{RecursionDesired: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// parseDNSAnswer decodes a response and returns its rcode and answers in
+// a readable form (e.g. "A 93.184.216.34")
+func parseDNSAnswer(msg []byte) (dnsmessage.RCode, []string, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(msg)
+	if err != nil {
+		return 0, nil, fmt.Errorf("decode DNS response: %w", err)
+	}
+	if !header.Response {
+		return 0, nil, fmt.Errorf("decode DNS response: message is not a response")
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return 0, nil, fmt.Errorf("decode DNS response: %w", err)
+	}
+	resources, err := p.AllAnswers()
+	if err != nil {
+		return 0, nil, fmt.Errorf("decode DNS response: %w", err)
+	}
+
+	answers := make([]string, 0, len(resources))
+	for _, r := range resources {
+		answers = append(answers, formatDNSResource(r))
+	}
+	return header.RCode, answers, nil
+}
+
+func formatDNSResource(r dnsmessage.Resource) string {
+	switch body := r.Body.(type) {
+	case *dnsmessage.AResource:
+		return "A " + net.IP(body.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return "AAAA " + net.IP(body.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		return "CNAME " + body.CNAME.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("MX %d %s", body.Pref, body.MX.String())
+	case *dnsmessage.NSResource:
+		return "NS " + body.NS.String()
+	case *dnsmessage.TXTResource:
+		return "TXT " + strings.Join(body.TXT, "")
+	}
+	return r.Header.Type.String()
+}
+
+// dnsQueryFor resolves the query name and type for a DoH/DoT host
+func dnsQueryFor(host Host, name string) ([]byte, error) {
+	if v := host.Tokens.Get("query"); v != "" {
+		name = v
+	}
+	if name == "" {
+		name = defaultQueryName
+	}
+	qtype := dnsmessage.TypeA
+	if v := host.Tokens.Get("qtype"); v != "" {
+		t, ok := dnsQueryTypes[strings.ToUpper(v)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported qtype %q", v)
+		}
+		qtype = t
+	}
+	return buildDNSQuery(name, qtype)
+}
+
+// evaluateDNSAnswer records the answer details and passes on NOERROR
+func evaluateDNSAnswer(ctx context.Context, msg []byte) (bool, error) {
+	rcode, answers, err := parseDNSAnswer(msg)
+	if err != nil {
+		return false, err
+	}
+	SetDetail(ctx, "rcode", strings.TrimPrefix(rcode.String(), "RCode"))
+	SetDetail(ctx, "answers", answers)
+	if rcode != dnsmessage.RCodeSuccess {
+		return false, fmt.Errorf("resolver answered %s", strings.TrimPrefix(rcode.String(), "RCode"))
+	}
+	return true, nil
+}
+
+// DoHCheck sends a DNS query over HTTPS (RFC 8484) to a resolver URL such as
+// "https://dns.example/dns-query?name=example.com"
+func DoHCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	timeout, err := opts.timeoutFor(host, defaultHTTPTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	endpoint := host.HostName
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false, fmt.Errorf("invalid DoH URL %q: %w", host.HostName, err)
+	}
+
+	// name= in the URL is ours; the resolver gets dns=
+	params := u.Query()
+	query, err := dnsQueryFor(host, params.Get("name"))
+	if err != nil {
+		return false, err
+	}
+	params.Del("name")
+	params.Set("dns", base64.RawURLEncoding.EncodeToString(query))
+	u.RawQuery = params.Encode()
+
+	tlsConf, err := tlsConfigFor(host, opts)
+	if err != nil {
+		return false, err
+	}
+	client := newHTTPClient(tlsConf, timeout, opts)
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", dnsMessageType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, describeTLSError(err)
+	}
+	defer resp.Body.Close()
+
+	SetDetail(ctx, "httpStatus", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return false, &StatusError{Code: resp.StatusCode}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDNSMessage))
+	if err != nil {
+		return false, fmt.Errorf("read DoH response: %w", err)
+	}
+	return evaluateDNSAnswer(ctx, body)
+}
+
+// DoTCheck sends a DNS query over TLS (RFC 7858) to host[:port], port 853 by
+// default. The query name comes from query=.
+func DoTCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	timeout, err := opts.timeoutFor(host, defaultHTTPTimeout)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := hostAddr(host.HostName, "DOT")
+	serverName, _, _ := net.SplitHostPort(addr)
+
+	query, err := dnsQueryFor(host, "")
+	if err != nil {
+		return false, err
+	}
+	tlsConf, err := tlsConfigFor(host, opts)
+	if err != nil {
+		return false, err
+	}
+	tlsConf.ServerName = serverName
+
+	tlsConn, err := tlsHandshake(ctx, addr, tlsConf, opts)
+	if err != nil {
+		return false, err
+	}
+	defer tlsConn.Close()
+	SetDetail(ctx, "tlsVersion", tls.VersionName(tlsConn.ConnectionState().Version))
+
+	// DNS over TCP frames each message with a two-byte length
+	var frame bytes.Buffer
+	binary.Write(&frame, binary.BigEndian, uint16(len(query)))
+	frame.Write(query)
+	if _, err := tlsConn.Write(frame.Bytes()); err != nil {
+		return false, fmt.Errorf("send DoT query: %w", err)
+	}
+
+	var length uint16
+	if err := binary.Read(tlsConn, binary.BigEndian, &length); err != nil {
+		return false, fmt.Errorf("read DoT response: %w", err)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(tlsConn, msg); err != nil {
+		return false, fmt.Errorf("read DoT response: %w", err)
+	}
+	return evaluateDNSAnswer(ctx, msg)
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DNSCache is a Resolver that reuses each name's addresses for a fixed TTL
+// before looking the name up again, so a long run (--repeat,
+// --wait-for-healthy) doesn't query DNS for every check yet still follows a
+// failover within the TTL. Failed lookups aren't cached. When a fresh
+// lookup returns different addresses than the expired entry, the check's
+// dnsChanged detail records the change.
+type DNSCache struct {
+	base Resolver
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+// dnsEntry is a cached lookup and when it stops being reused
+type dnsEntry struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// NewDNSCache returns a cache in front of base (net.DefaultResolver when
+// nil) that reuses lookups for ttl
+func NewDNSCache(base Resolver, ttl time.Duration) *DNSCache {
+	if base == nil {
+		base = net.DefaultResolver
+	}
+	return &DNSCache{base: base, ttl: ttl, entries: map[string]dnsEntry{}}
+}
+
+// LookupIPAddr returns the cached addresses for host while they're fresh,
+// otherwise looks host up again
+func (c *DNSCache) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	key := strings.ToLower(host)
+	c.mu.Lock()
+	old, cached := c.entries[key]
+	c.mu.Unlock()
+	if cached && time.Now().Before(old.expires) {
+		return slices.Clone(old.addrs), nil
+	}
+
+	addrs, err := c.base.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[key] = dnsEntry{addrs: slices.Clone(addrs), expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	if before, after := addrList(old.addrs), addrList(addrs); cached && before != after {
+		SetDetail(ctx, "dnsChanged", fmt.Sprintf("%s: %s -> %s", host, before, after))
+	}
+	return addrs, nil
+}
+
+// addrList renders addresses sorted and comma-separated, so two lookups
+// returning the same set in a different order compare equal
+func addrList(addrs []net.IPAddr) string {
+	list := make([]string, len(addrs))
+	for i, addr := range addrs {
+		list[i] = addr.String()
+	}
+	slices.Sort(list)
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+)
+
+// Address families for the ipv= token. ipv=both is split into a v4 and a
+// v6 host when the config is loaded (see SplitDualStack), so checks only
+// ever see one family.
+const (
+	FamilyV4   = "4"
+	FamilyV6   = "6"
+	FamilyBoth = "both"
+)
+
+// familyKey carries a check's address family in its context, so the
+// shared dialers can honour it without every check passing it along
+type familyKey struct{}
+
+// ipFamily reads the host's ipv= token: FamilyV4, FamilyV6, or "" when the
+// check may use either
+func ipFamily(host Host) (string, error) {
+	switch v := host.Tokens.Get("ipv"); v {
+	case "", FamilyV4, FamilyV6:
+		return v, nil
+	case FamilyBoth:
+		return "", fmt.Errorf("ipv=both must be split before the check runs")
+	default:
+		return "", fmt.Errorf("invalid ipv %q (valid: 4, 6, both)", v)
+	}
+}
+
+// withIPFamily records the host's ipv= family in ctx. A proxy resolves
+// names itself and a via= check runs elsewhere, so neither can be pinned.
+func withIPFamily(ctx context.Context, host Host, opts *Options) (context.Context, error) {
+	if !SupportsIPFamily(host.CheckType) {
+		// UnknownTokens already flags ipv= on other check types
+		return ctx, nil
+	}
+	family, err := ipFamily(host)
+	if err != nil || family == "" {
+		return ctx, err
+	}
+	if opts.proxied() {
+		return ctx, fmt.Errorf("ipv= can't be used through a SOCKS5 proxy")
+	}
+	if host.Tokens.Has("via") {
+		return ctx, fmt.Errorf("ipv= can't be combined with via=")
+	}
+	return context.WithValue(ctx, familyKey{}, family), nil
+}
+
+// familyFrom returns the address family recorded in ctx, "" for either
+func familyFrom(ctx context.Context) string {
+	family, _ := ctx.Value(familyKey{}).(string)
+	return family
+}
+
+// familyNetwork pins a "tcp" or "udp" network to the family, e.g. tcp6
+func familyNetwork(network, family string) string {
+	if family != "" && (network == "tcp" || network == "udp") {
+		return network + family
+	}
+	return network
+}
+
+// inFamily reports whether ip belongs to the family; every address does
+// when the family is ""
+func inFamily(ip net.IP, family string) bool {
+	switch family {
+	case FamilyV4:
+		return ip.To4() != nil
+	case FamilyV6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// familyAddrs keeps the addresses in the family
+func familyAddrs(ips []net.IPAddr, family string) []net.IPAddr {
+	if family == "" {
+		return ips
+	}
+	var kept []net.IPAddr
+	for _, ip := range ips {
+		if inFamily(ip.IP, family) {
+			kept = append(kept, ip)
+		}
+	}
+	return kept
+}
+
+// noFamilyAddress is the lookup error for a name with addresses, just none
+// in the check's family
+func noFamilyAddress(name, family string) error {
+	return &net.DNSError{Err: "no IPv" + family + " address", Name: name, IsNotFound: true}
+}
+
+// HasFamilyAddress reports whether name (a hostname or IP literal) has an
+// address in the family, resolving with opts.Resolver when set. Lookup
+// failures are returned as is, so the caller can leave them to the check.
+func HasFamilyAddress(ctx context.Context, name, family string, opts *Options) (bool, error) {
+	if ip := net.ParseIP(name); ip != nil {
+		return inFamily(ip, family), nil
+	}
+	ips, err := opts.resolver().LookupIPAddr(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	return len(familyAddrs(ips, family)) > 0, nil
+}
+
+// SplitDualStack replaces each ipv=both host, and with all every host that
+// doesn't set ipv=, with a v4 and a v6 host. Check types that don't honour
+// ipv= are left alone, and so are via= hosts under all, since they can't be
+// pinned to a family (an explicit ipv=both on one is reported by the check).
+// The halves are labelled "name (v4)" and "name (v6)", get distinct IDs (an
+// id= gains a -v4 or -v6 suffix), and record the original name in
+// DualStack.
+func SplitDualStack(hosts []Host, all bool) []Host {
+	split := make([]Host, 0, len(hosts))
+	for _, host := range hosts {
+		v := host.Tokens.Get("ipv")
+		implicit := all && v == "" && !host.Tokens.Has("via")
+		if !SupportsIPFamily(host.CheckType) || v != FamilyBoth && !implicit {
+			split = append(split, host)
+			continue
+		}
+		for _, family := range []string{FamilyV4, FamilyV6} {
+			half := host
+			half.Tokens = maps.Clone(host.Tokens)
+			if half.Tokens == nil {
+				half.Tokens = Tokens{}
+			}
+			half.Tokens["ipv"] = []string{family}
+			if id := host.Tokens.Get("id"); id != "" {
+				half.Tokens["id"] = []string{id + "-v" + family}
+			}
+			half.Label = fmt.Sprintf("%s (v%s)", host.DisplayName(), family)
+			half.DualStack = host.DisplayName()
+			split = append(split, half)
+		}
+	}
+	return split
+}
+
+// SupportsIPFamily reports whether checks of the type honour ipv=
+func SupportsIPFamily(checkType string) bool {
+	return slices.ContainsFunc(checkTypeTokens[checkType], func(g []string) bool { return slices.Contains(g, "ipv") })
+}
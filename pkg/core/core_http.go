@@ -0,0 +1,450 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultMaxBodySize bounds how much of a response body a check reads when
+// neither --max-body-size nor maxbody= is set
+const defaultMaxBodySize = 10 << 20
+
+// defaultBodyIdleTimeout is how long a body assertion waits for the next
+// chunk of a response body before giving up (override with bodytimeout=)
+const defaultBodyIdleTimeout = 2 * time.Second
+
+// BodyTimeoutError reports a response body that stopped arriving after the
+// headers were received, as opposed to a connection or header timeout
+type BodyTimeoutError struct {
+	// Bytes is how much of the body arrived before the read timed out
+	Bytes int64
+	// Idle is the stall that tripped the bodytimeout= deadline; zero when
+	// the overall check timeout expired instead
+	Idle time.Duration
+}
+
+func (e *BodyTimeoutError) Error() string {
+	if e.Idle > 0 {
+		return fmt.Sprintf("body read timed out after %d bytes (no data for %s)", e.Bytes, e.Idle)
+	}
+	return fmt.Sprintf("body read timed out after %d bytes", e.Bytes)
+}
+
+// Timeout and Temporary make the error a net.Error so --retry-on timeout
+// covers stalled bodies too
+func (e *BodyTimeoutError) Timeout() bool   { return true }
+func (e *BodyTimeoutError) Temporary() bool { return true }
+
+// deadlineReader fails a body read that makes no progress within idle,
+// counting the bytes delivered so far
+type deadlineReader struct {
+	body io.ReadCloser
+	idle time.Duration
+	n    int64
+}
+
+func newDeadlineReader(body io.ReadCloser, idle time.Duration) *deadlineReader {
+	return &deadlineReader{body: body, idle: idle}
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	type readResult struct {
+		n   int
+		err error
+	}
+
+	// Read into a private buffer so an abandoned read can't write into p
+	buf := make([]byte, len(p))
+	done := make(chan readResult, 1)
+	go func() {
+		n, err := r.body.Read(buf)
+		done <- readResult{n, err}
+	}()
+
+	timer := time.NewTimer(r.idle)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		n := copy(p, buf[:res.n])
+		r.n += int64(n)
+		var netErr net.Error
+		if res.err != nil && errors.As(res.err, &netErr) && netErr.Timeout() {
+			return n, &BodyTimeoutError{Bytes: r.n}
+		}
+		return n, res.err
+	case <-timer.C:
+		// Closing the body unblocks the pending read
+		r.body.Close()
+		return 0, &BodyTimeoutError{Bytes: r.n, Idle: r.idle}
+	}
+}
+
+// BodyTooLargeError reports a response body longer than the max body size
+// (--max-body-size or maxbody=). Assertions that need the whole body fail
+// with it rather than judging a truncated prefix.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("body exceeded max size %d bytes", e.Limit)
+}
+
+// maxBodySize resolves the host's maxbody= token, then --max-body-size
+func (o *Options) maxBodySize(host Host) (int64, error) {
+	if v := host.Tokens.Get("maxbody"); v != "" {
+		n, err := ParseSize(v)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid maxbody %q", v)
+		}
+		return n, nil
+	}
+	if o != nil && o.MaxBodySize > 0 {
+		return o.MaxBodySize, nil
+	}
+	return defaultMaxBodySize, nil
+}
+
+// cappedBody delivers at most limit bytes of a response body, then fails
+// with *BodyTooLargeError if there's more
+type cappedBody struct {
+	io.ReadCloser
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func newCappedBody(body io.ReadCloser, limit int64) *cappedBody {
+	// One byte past the limit tells a body of exactly limit bytes apart
+	// from a longer one
+	return &cappedBody{ReadCloser: body, r: io.LimitReader(body, limit+1), limit: limit}
+}
+
+func (c *cappedBody) Read(p []byte) (int, error) {
+	// Past the limit every read fails the same way, delivering nothing
+	if c.n > c.limit {
+		return 0, &BodyTooLargeError{Limit: c.limit}
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.n > c.limit {
+		return max(0, n-int(c.n-c.limit)), &BodyTooLargeError{Limit: c.limit}
+	}
+	return n, err
+}
+
+// bodyCapTransport caps every response body it returns
+type bodyCapTransport struct {
+	base  http.RoundTripper
+	limit int64
+}
+
+func (t *bodyCapTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = newCappedBody(resp.Body, t.limit)
+	return resp, nil
+}
+
+// CloseIdleConnections passes through so http.Client.CloseIdleConnections
+// still reaches the transport
+func (t *bodyCapTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// bodyIdleTimeout resolves the host's bodytimeout= token
+func bodyIdleTimeout(host Host) (time.Duration, error) {
+	v := host.Tokens.Get("bodytimeout")
+	if v == "" {
+		return defaultBodyIdleTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid bodytimeout %q", v)
+	}
+	return d, nil
+}
+
+// defaultBodyContentType is sent with body= payloads unless contenttype= is set
+const defaultBodyContentType = "application/json"
+
+// checkMethod resolves the method= token. Without one, checks GET - or POST
+// when a body= payload is configured.
+func checkMethod(host Host) string {
+	if m := host.Tokens.Get("method"); m != "" {
+		return strings.ToUpper(m)
+	}
+	if host.Tokens.Has("body") {
+		return http.MethodPost
+	}
+	return http.MethodGet
+}
+
+// requestPayload loads the body= token: inline text, or "@path" to read a
+// file whose ${VAR} references are expanded
+func requestPayload(host Host) ([]byte, error) {
+	body := host.Tokens.Get("body")
+	path, fromFile := strings.CutPrefix(body, "@")
+	if !fromFile {
+		return []byte(body), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read body payload: %w", err)
+	}
+	return []byte(ExpandVars(string(data))), nil
+}
+
+// newCheckRequest builds the request for an HTTP-family check, attaching the
+// body= payload when set. The payload is held in memory so the request can
+// be replayed on redirects (GetBody) and rebuilt for each retry.
+func newCheckRequest(ctx context.Context, host Host, method, url string) (*http.Request, error) {
+	if !host.Tokens.Has("body") {
+		return http.NewRequestWithContext(ctx, method, url, nil)
+	}
+
+	payload, err := requestPayload(host)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	contentType := host.Tokens.Get("contenttype")
+	if contentType == "" {
+		contentType = defaultBodyContentType
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+// Framing values for transfer= and the transferEncoding detail
+const (
+	transferChunked = "chunked"
+	transferLength  = "length"
+	// transferNone is a body delimited by the connection closing (HTTP/1.0
+	// style) or an HTTP/2 stream without a Content-Length
+	transferNone = "none"
+	// transferUnknown is a gzip body the transport decompressed, which
+	// drops its Content-Length
+	transferUnknown = "unknown"
+)
+
+// responseTransfer reports how a response body was framed
+func responseTransfer(resp *http.Response) string {
+	switch {
+	case slices.Contains(resp.TransferEncoding, "chunked"):
+		return transferChunked
+	case resp.ContentLength >= 0:
+		return transferLength
+	case resp.Uncompressed:
+		return transferUnknown
+	}
+	return transferNone
+}
+
+// checkTransfer enforces transfer=chunked|length: the response must use
+// chunked transfer encoding, or a fixed Content-Length. The detected
+// framing is reported as the transferEncoding detail.
+func checkTransfer(ctx context.Context, host Host, resp *http.Response) error {
+	want := strings.ToLower(host.Tokens.Get("transfer"))
+	if want == "" {
+		return nil
+	}
+	if want != transferChunked && want != transferLength {
+		return fmt.Errorf("invalid transfer %q: want chunked or length", want)
+	}
+	got := responseTransfer(resp)
+	SetDetail(ctx, "transferEncoding", got)
+	if got == transferUnknown && want == transferLength {
+		return fmt.Errorf("can't tell whether the decompressed gzip response had a Content-Length")
+	}
+	if got != want {
+		return fmt.Errorf("response transfer is %s, want %s", got, want)
+	}
+	return nil
+}
+
+// statusAccepted is the status code rule: 200 OK or 404 Not Found pass
+func statusAccepted(code int) bool {
+	return code == http.StatusOK || code == http.StatusNotFound
+}
+
+// evaluateResponse applies the status code rule and any per-host body
+// assertions to an HTTP response
+func evaluateResponse(host Host, resp *http.Response) error {
+	if !statusAccepted(resp.StatusCode) {
+		return &StatusError{Code: resp.StatusCode}
+	}
+
+	if err := checkSetCookies(host, resp); err != nil {
+		return err
+	}
+	if err := checkAbsentHeaders(host, resp); err != nil {
+		return err
+	}
+	if err := checkSchema(host, resp); err != nil {
+		return err
+	}
+	return checkBodySize(host, resp)
+}
+
+// checkSetCookies enforces setcookie= tokens: "session;secure;httponly"
+// requires a Set-Cookie for session with each listed attribute. samesite=
+// may name the expected mode (samesite=strict). Only the final response is
+// inspected, not redirects along the way.
+func checkSetCookies(host Host, resp *http.Response) error {
+	specs := host.Tokens.Values("setcookie")
+	if len(specs) == 0 {
+		return nil
+	}
+	cookies := map[string]*http.Cookie{}
+	for _, c := range resp.Cookies() {
+		cookies[c.Name] = c
+	}
+
+	for _, spec := range specs {
+		parts := strings.Split(spec, ";")
+		name := strings.TrimSpace(parts[0])
+		if name == "" {
+			return fmt.Errorf("invalid setcookie %q: missing cookie name", spec)
+		}
+		cookie, ok := cookies[name]
+		if !ok {
+			return fmt.Errorf("response didn't set cookie %s", name)
+		}
+		for _, attr := range parts[1:] {
+			attr = strings.ToLower(strings.TrimSpace(attr))
+			key, want, _ := strings.Cut(attr, "=")
+			switch key {
+			case "secure":
+				if !cookie.Secure {
+					return fmt.Errorf("cookie %s is missing the Secure attribute", name)
+				}
+			case "httponly":
+				if !cookie.HttpOnly {
+					return fmt.Errorf("cookie %s is missing the HttpOnly attribute", name)
+				}
+			case "samesite":
+				got := sameSiteNames[cookie.SameSite]
+				if got == "" {
+					return fmt.Errorf("cookie %s is missing the SameSite attribute", name)
+				}
+				if want != "" && got != want {
+					return fmt.Errorf("cookie %s has SameSite=%s, want %s", name, got, want)
+				}
+			default:
+				return fmt.Errorf("invalid setcookie %q: unknown attribute %q (valid: secure, httponly, samesite)", spec, attr)
+			}
+		}
+	}
+	return nil
+}
+
+// checkAbsentHeaders enforces noheader= tokens, failing when the final
+// response carries a header that leaks server details. "Server~=nginx"
+// only fails when a value of the header matches the regular expression.
+func checkAbsentHeaders(host Host, resp *http.Response) error {
+	for _, spec := range host.Tokens.Values("noheader") {
+		name, pattern, hasPattern := strings.Cut(spec, "~=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("invalid noheader %q: missing header name", spec)
+		}
+		var re *regexp.Regexp
+		if hasPattern {
+			var err error
+			if re, err = regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid noheader %q: %w", spec, err)
+			}
+		}
+		for _, value := range resp.Header.Values(name) {
+			if re == nil || re.MatchString(value) {
+				return fmt.Errorf("response leaks header %s: %q", http.CanonicalHeaderKey(name), value)
+			}
+		}
+	}
+	return nil
+}
+
+// sameSiteNames maps parsed SameSite modes to their attribute values
+var sameSiteNames = map[http.SameSite]string{
+	http.SameSiteLaxMode:    "lax",
+	http.SameSiteStrictMode: "strict",
+	http.SameSiteNoneMode:   "none",
+}
+
+// checkBodySize enforces the minsize/maxsize tokens. The body is counted as
+// it is read so chunked responses without a Content-Length are handled too.
+func checkBodySize(host Host, resp *http.Response) error {
+	if !host.Tokens.Has("minsize") && !host.Tokens.Has("maxsize") {
+		return nil
+	}
+
+	minSize, maxSize := int64(0), int64(-1)
+	if v := host.Tokens.Get("minsize"); v != "" {
+		n, err := ParseSize(v)
+		if err != nil {
+			return fmt.Errorf("minsize: %w", err)
+		}
+		minSize = n
+	}
+	if v := host.Tokens.Get("maxsize"); v != "" {
+		n, err := ParseSize(v)
+		if err != nil {
+			return fmt.Errorf("maxsize: %w", err)
+		}
+		maxSize = n
+	}
+	idle, err := bodyIdleTimeout(host)
+	if err != nil {
+		return err
+	}
+
+	// Read one byte past maxsize so an oversized body is detected without
+	// reading all of it; minsize alone only needs that many bytes
+	limit := minSize
+	if maxSize >= 0 {
+		limit = maxSize + 1
+	}
+	size, err := io.Copy(io.Discard, io.LimitReader(newDeadlineReader(resp.Body, idle), limit))
+	if err != nil {
+		var bodyErr *BodyTimeoutError
+		if errors.As(err, &bodyErr) {
+			return bodyErr
+		}
+		var tooLarge *BodyTooLargeError
+		if errors.As(err, &tooLarge) {
+			return tooLarge
+		}
+		return fmt.Errorf("read body after %d bytes: %w", size, err)
+	}
+
+	if maxSize >= 0 && size > maxSize {
+		// The full size is only known up front when Content-Length was sent
+		if resp.ContentLength > 0 {
+			return fmt.Errorf("body size %d bytes exceeds maxsize %d bytes", resp.ContentLength, maxSize)
+		}
+		return fmt.Errorf("body size exceeds maxsize %d bytes (read more than %d bytes)", maxSize, maxSize)
+	}
+	if size < minSize {
+		return fmt.Errorf("body size %d bytes below minsize %d bytes", size, minSize)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ICMP modes for --icmp-mode. Auto tries an unprivileged datagram socket,
+// then a raw socket, then the ping command; the others use just one.
+const (
+	ICMPModeAuto  = "auto"
+	ICMPModeDgram = "dgram"
+	ICMPModeRaw   = "raw"
+	ICMPModeExec  = "exec"
+)
+
+// ICMP protocol numbers for icmp.ParseMessage
+const (
+	protocolICMP     = 1
+	protocolICMPIPv6 = 58
+)
+
+// ValidateICMPMode checks an --icmp-mode value
+func ValidateICMPMode(mode string) error {
+	switch mode {
+	case ICMPModeAuto, ICMPModeDgram, ICMPModeRaw, ICMPModeExec:
+		return nil
+	}
+	return fmt.Errorf("invalid ICMP mode %q (valid: auto, dgram, raw, exec)", mode)
+}
+
+// icmpSocketModes returns the native socket modes to try, in order. Auto
+// only goes native on Linux, where unprivileged datagram sockets are
+// common, and never when --ping-bin asks for a particular command.
+func (o *Options) icmpSocketModes() []string {
+	mode := ICMPModeAuto
+	if o != nil && o.ICMPMode != "" {
+		mode = o.ICMPMode
+	}
+	switch mode {
+	case ICMPModeDgram, ICMPModeRaw:
+		return []string{mode}
+	case ICMPModeAuto:
+		if runtime.GOOS == "linux" && (o == nil || o.PingBin == "") {
+			return []string{ICMPModeDgram, ICMPModeRaw}
+		}
+	}
+	return nil
+}
+
+// listenICMP opens an ICMP socket: a datagram one ("udp4", allowed for
+// unprivileged users by net.ipv4.ping_group_range) or a raw one, which
+// needs root or CAP_NET_RAW
+func listenICMP(mode string, v6 bool) (*icmp.PacketConn, error) {
+	network := map[string][2]string{
+		ICMPModeDgram: {"udp4", "udp6"},
+		ICMPModeRaw:   {"ip4:icmp", "ip6:ipv6-icmp"},
+	}[mode]
+	if v6 {
+		return icmp.ListenPacket(network[1], "::")
+	}
+	return icmp.ListenPacket(network[0], "0.0.0.0")
+}
+
+// pingAddr resolves name to the address a native ping sends to, honouring
+// the check's ipv= family
+func (o *Options) pingAddr(ctx context.Context, name string) (net.IP, error) {
+	family := familyFrom(ctx)
+	if ip := net.ParseIP(name); ip != nil {
+		if !inFamily(ip, family) {
+			return nil, fmt.Errorf("%s is not an IPv%s address", name, family)
+		}
+		return ip, nil
+	}
+	ips, err := o.resolver().LookupIPAddr(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("lookup %s: no addresses", name)
+	}
+	if ips = familyAddrs(ips, family); len(ips) == 0 {
+		return nil, noFamilyAddress(name, family)
+	}
+	return ips[0].IP, nil
+}
+
+// nativePing pings the host from an ICMP socket, trying each of modes in
+// turn. It reports false when none could be opened, so the caller falls
+// back to the ping command; the reasons are recorded as the icmpFallback
+// detail, and the mode that ran as icmpMode. A single mode is an explicit
+// --icmp-mode, whose socket error is the check's.
+func nativePing(ctx context.Context, host Host, opts *Options, modes []string, count int, timeout time.Duration) (pingStats, bool, error) {
+	ip, err := opts.pingAddr(ctx, host.HostName)
+	if err != nil {
+		return pingStats{}, true, err
+	}
+	v6 := ip.To4() == nil
+
+	var reasons []string
+	for _, mode := range modes {
+		conn, err := listenICMP(mode, v6)
+		if err != nil {
+			if len(modes) == 1 {
+				// An explicit --icmp-mode doesn't fall back
+				return pingStats{}, true, fmt.Errorf("open %s ICMP socket: %w", mode, err)
+			}
+			reasons = append(reasons, mode+": "+err.Error())
+			continue
+		}
+		defer conn.Close()
+		if len(reasons) > 0 {
+			SetDetail(ctx, "icmpFallback", strings.Join(reasons, "; "))
+		}
+		SetDetail(ctx, "icmpMode", mode)
+		stats, err := echoLoop(ctx, conn, mode, ip, count, timeout)
+		return stats, true, err
+	}
+	SetDetail(ctx, "icmpFallback", strings.Join(reasons, "; "))
+	return pingStats{}, false, nil
+}
+
+// echoLoop sends count echo requests a second apart, like ping, waiting up
+// to timeout for each reply
+func echoLoop(ctx context.Context, conn *icmp.PacketConn, mode string, ip net.IP, count int, timeout time.Duration) (pingStats, error) {
+	v6 := ip.To4() == nil
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if mode == ICMPModeDgram {
+		dst = &net.UDPAddr{IP: ip}
+	}
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	proto := protocolICMP
+	if v6 {
+		echoType, replyType, proto = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, protocolICMPIPv6
+	}
+	// Datagram sockets get their own ID from the kernel, which only hands
+	// them their own replies; raw sockets see every reply, so a random ID
+	// keeps concurrent checks apart
+	id := rand.IntN(0xffff) + 1
+
+	var stats pingStats
+	var totalRTT time.Duration
+	for seq := 1; seq <= count; seq++ {
+		started := time.Now()
+		msg := icmp.Message{Type: echoType, Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("netcheck")}}
+		packet, err := msg.Marshal(nil)
+		if err != nil {
+			return stats, err
+		}
+		if _, err := conn.WriteTo(packet, dst); err != nil {
+			return stats, fmt.Errorf("send echo request to %s: %w", ip, err)
+		}
+		stats.sent++
+
+		deadline := started.Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		rtt, err := awaitEchoReply(conn, mode, ip, proto, replyType, id, seq, deadline)
+		if err != nil {
+			return stats, err
+		}
+		if rtt > 0 {
+			stats.received++
+			totalRTT += rtt
+		}
+
+		if seq < count {
+			select {
+			case <-ctx.Done():
+				return stats, ctx.Err()
+			case <-time.After(time.Until(started.Add(time.Second))):
+			}
+		}
+	}
+	if stats.received > 0 {
+		stats.avgRTT = float64(totalRTT.Microseconds()) / 1000 / float64(stats.received)
+	}
+	return stats, nil
+}
+
+// awaitEchoReply reads until the reply to seq arrives or deadline passes,
+// returning its round trip, or 0 when it didn't come. Other ICMP traffic
+// (unreachables, replies to other pings) is skipped.
+func awaitEchoReply(conn *icmp.PacketConn, mode string, ip net.IP, proto int, replyType icmp.Type, id, seq int, deadline time.Time) (time.Duration, error) {
+	sent := time.Now()
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("read echo reply from %s: %w", ip, err)
+		}
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || msg.Type != replyType {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq {
+			continue
+		}
+		if mode == ICMPModeRaw && (echo.ID != id || !sameIP(from, ip)) {
+			continue
+		}
+		return max(time.Since(sent), time.Microsecond), nil
+	}
+}
+
+// sameIP reports whether a socket peer address is ip
+func sameIP(addr net.Addr, ip net.IP) bool {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	}
+	return false
+}
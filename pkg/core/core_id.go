@@ -0,0 +1,51 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// idLength is the number of hex characters in a derived check ID
+const idLength = 12
+
+// idIgnoredTokens don't change what a check probes, so editing them (or the
+// label) keeps the check's ID
+var idIgnoredTokens = map[string]bool{
+	"id":       true,
+	"depends":  true,
+	"maint":    true,
+	"severity": true,
+	"budget":   true,
+	"maxtime":  true,
+	"timeout":  true,
+	"diff":     true,
+	"version":  true,
+	"tag":      true,
+}
+
+// ID returns a stable identifier for the check: the id= token when set,
+// otherwise a hash of the check type, hostname (including any port), and
+// the tokens that shape the check, as written in the config
+func (h Host) ID() string {
+	if id := h.Tokens.Get("id"); id != "" {
+		return id
+	}
+
+	keys := make([]string, 0, len(h.Tokens))
+	for key := range h.Tokens {
+		if !idIgnoredTokens[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	sum := sha256.New()
+	sum.Write([]byte(h.CheckType + "\x00" + h.HostName))
+	for _, key := range keys {
+		for _, value := range h.Tokens[key] {
+			sum.Write([]byte("\x00" + key + "=" + value))
+		}
+	}
+	return hex.EncodeToString(sum.Sum(nil))[:idLength]
+}
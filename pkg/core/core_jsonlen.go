@@ -0,0 +1,117 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// reJSONLen splits a jsonlen= spec such as ".nodes>=3" into path,
+// comparison, and count
+var reJSONLen = regexp.MustCompile(`^([^<>=!]*)(>=|<=|==|!=|>|<|=)\s*([0-9]+)$`)
+
+// jsonLenCompare holds the jsonlen= comparisons
+var jsonLenCompare = map[string]func(got, want int) bool{
+	">=": func(got, want int) bool { return got >= want },
+	"<=": func(got, want int) bool { return got <= want },
+	">":  func(got, want int) bool { return got > want },
+	"<":  func(got, want int) bool { return got < want },
+	"==": func(got, want int) bool { return got == want },
+	"=":  func(got, want int) bool { return got == want },
+	"!=": func(got, want int) bool { return got != want },
+}
+
+// checkJSONLen enforces jsonlen= tokens on HTTP and HTPS checks: the array
+// at a dotted path in the JSON body (".nodes", or "." for the body itself)
+// must have a length satisfying the comparison. Lengths are reported in
+// the jsonLen detail, keyed by path.
+func checkJSONLen(ctx context.Context, host Host, resp *http.Response) error {
+	specs := host.Tokens.Values("jsonlen")
+	if len(specs) == 0 || !statusAccepted(resp.StatusCode) {
+		// evaluateResponse fails an error status; its body isn't the array
+		return nil
+	}
+
+	body, err := bufferBody(host, resp, "jsonlen=")
+	if err != nil {
+		return err
+	}
+	lengths := map[string]int{}
+	defer func() {
+		if len(lengths) > 0 {
+			SetDetail(ctx, "jsonLen", lengths)
+		}
+	}()
+	for _, spec := range specs {
+		m := reJSONLen.FindStringSubmatch(strings.TrimSpace(spec))
+		if m == nil {
+			return fmt.Errorf("invalid jsonlen %q: want PATH>=N (e.g. .nodes>=3)", spec)
+		}
+		path, op := strings.TrimSpace(m[1]), m[2]
+		want, err := strconv.Atoi(m[3])
+		if err != nil {
+			return fmt.Errorf("invalid jsonlen %q: %w", spec, err)
+		}
+		value, err := jsonValue(body, path)
+		if err != nil {
+			return fmt.Errorf("jsonlen: %w", err)
+		}
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("jsonlen: %s isn't an array", jsonPathName(path))
+		}
+		lengths[jsonPathName(path)] = len(items)
+		if !jsonLenCompare[op](len(items), want) {
+			return fmt.Errorf("%s has %d items, want %s%d", jsonPathName(path), len(items), op, want)
+		}
+	}
+	return nil
+}
+
+// jsonValue decodes a JSON body and returns the value at a dotted path of
+// object keys and array indexes. A leading dot is optional, and "" or "."
+// is the whole body.
+func jsonValue(body []byte, path string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("body isn't JSON: %w", err)
+	}
+	trimmed := strings.TrimPrefix(path, ".")
+	if trimmed == "" {
+		return value, nil
+	}
+	for _, key := range strings.Split(trimmed, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			item, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("no field %s in the body", path)
+			}
+			value = item
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("no field %s in the body", path)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("no field %s in the body", path)
+		}
+	}
+	return value, nil
+}
+
+// jsonPathName is how a jsonlen= path appears in errors and details
+func jsonPathName(path string) string {
+	if path == "" || path == "." {
+		return "body"
+	}
+	return path
+}
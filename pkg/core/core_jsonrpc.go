@@ -0,0 +1,152 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// jsonRPCID is the id of the one request a JRPC check sends
+const jsonRPCID = 1
+
+// jsonRPCRequest is a JSON-RPC 2.0 call
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      int             `json:"id"`
+}
+
+// jsonRPCResponse is a JSON-RPC 2.0 response. Result stays raw so a null
+// result can be told apart from a missing one.
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *JSONRPCError   `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// JSONRPCError is the error object of a JSON-RPC response
+type JSONRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+// JSONRPCCheck POSTs a JSON-RPC 2.0 call of the method= method, with the
+// params= params, to an endpoint URL such as "https://api.example/rpc"
+// (https:// when no scheme is given). It passes when the response carries a
+// result; an error object fails the check with its code and message. The
+// HTTP status only matters when the body isn't a JSON-RPC response, since
+// servers answer errors with 200 or 500 alike.
+func JSONRPCCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	method := host.Tokens.Get("method")
+	if method == "" {
+		return false, fmt.Errorf("JRPC checks need method=")
+	}
+	params, err := jsonRPCParams(host)
+	if err != nil {
+		return false, err
+	}
+	timeout, err := opts.timeoutFor(host, defaultHTTPTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	endpoint := host.HostName
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false, fmt.Errorf("invalid JSON-RPC URL %q: %w", host.HostName, err)
+	}
+
+	tlsConf, err := tlsConfigFor(host, opts)
+	if err != nil {
+		return false, err
+	}
+	client, err := newCheckClient(host, tlsConf, timeout, opts)
+	if err != nil {
+		return false, err
+	}
+	defer client.CloseIdleConnections()
+
+	payload, err := json.Marshal(jsonRPCRequest{JSONRPC: "2.0", Method: method, Params: params, ID: jsonRPCID})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, describeTLSError(err)
+	}
+	defer resp.Body.Close()
+
+	SetDetail(ctx, "httpStatus", resp.StatusCode)
+	body, err := bufferBody(host, resp, "JSON-RPC response")
+	if err != nil {
+		return false, err
+	}
+	return evaluateJSONRPCResponse(ctx, resp.StatusCode, body)
+}
+
+// jsonRPCParams loads the params= token: a JSON array or object, inline or
+// "@path" to read a file whose ${VAR} references are expanded. Nil when
+// unset, so the call has no params member.
+func jsonRPCParams(host Host) (json.RawMessage, error) {
+	v := host.Tokens.Get("params")
+	if v == "" {
+		return nil, nil
+	}
+	data := []byte(v)
+	if path, fromFile := strings.CutPrefix(v, "@"); fromFile {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read params: %w", err)
+		}
+		data = []byte(ExpandVars(string(raw)))
+	}
+	data = bytes.TrimSpace(data)
+	if !json.Valid(data) || data[0] != '[' && data[0] != '{' {
+		return nil, fmt.Errorf("invalid params: want a JSON array or object")
+	}
+	return data, nil
+}
+
+// evaluateJSONRPCResponse judges a JSON-RPC response body, recording an
+// error object's code as the rpcErrorCode detail
+func evaluateJSONRPCResponse(ctx context.Context, status int, body []byte) (bool, error) {
+	var rpcResp jsonRPCResponse
+	if err := json.Unmarshal(body, &rpcResp); err != nil || rpcResp.JSONRPC != "2.0" {
+		if status != http.StatusOK {
+			return false, &StatusError{Code: status}
+		}
+		return false, fmt.Errorf("not a JSON-RPC 2.0 response")
+	}
+	if rpcResp.Error != nil {
+		SetDetail(ctx, "rpcErrorCode", rpcResp.Error.Code)
+		return false, rpcResp.Error
+	}
+	if rpcResp.Result == nil {
+		return false, fmt.Errorf("JSON-RPC response has neither result nor error")
+	}
+	if id := string(rpcResp.ID); id != fmt.Sprint(jsonRPCID) {
+		return false, fmt.Errorf("JSON-RPC response id %s doesn't match request id %d", id, jsonRPCID)
+	}
+	return true, nil
+}
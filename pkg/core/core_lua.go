@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// luaAbandonGrace is how long a Lua script gets to unwind after its state
+// is force-closed at the deadline before the check stops waiting for it
+const luaAbandonGrace = 2 * time.Second
+
+// ErrScriptAbandoned reports a script that didn't stop at its deadline. Its
+// goroutine is left behind so the run can move on.
+var ErrScriptAbandoned = errors.New("script ignored its deadline and was abandoned")
+
+// luaOutcome is what a Lua script run reports back to its check
+type luaOutcome struct {
+	passed bool
+	err    error
+}
+
+// runLua executes a Lua script in its own goroutine. SetContext stops the
+// VM between instructions, but a script blocked inside a Go function (e.g.
+// os.execute or io.read) never gets there, so on ctx expiry the state is
+// force-closed, and if the script still hasn't returned after
+// luaAbandonGrace it is abandoned with ErrScriptAbandoned.
+func runLua(ctx context.Context, scriptPath, hostname string, env []string, data *ScriptData, timeout time.Duration) (bool, error) {
+	L := lua.NewState()
+	var closeOnce sync.Once
+	closeState := func() { closeOnce.Do(L.Close) }
+	L.SetContext(ctx)
+
+	// Set hostname as global variable for the script
+	L.SetGlobal("hostname", lua.LString(hostname))
+	L.SetGlobal("env", luaEnvTable(L, env))
+	if data != nil {
+		L.SetGlobal("data", luaValue(L, data.Value))
+	}
+
+	done := make(chan luaOutcome, 1)
+	go func() {
+		defer func() {
+			// Closing the state under a running script makes it panic
+			if r := recover(); r != nil {
+				done <- luaOutcome{err: fmt.Errorf("lua script stopped: %v", r)}
+			}
+		}()
+		passed, err := evalLua(L, scriptPath)
+		done <- luaOutcome{passed: passed, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		closeState()
+		return out.passed, out.err
+	case <-ctx.Done():
+	}
+
+	closeState()
+	select {
+	case <-done:
+		return false, fmt.Errorf("lua script timed out after %s: %w", timeout, ctx.Err())
+	case <-time.After(luaAbandonGrace):
+		return false, fmt.Errorf("lua script timed out after %s: %w", timeout, ErrScriptAbandoned)
+	}
+}
+
+// luaEnvTable builds the script's env global: the process environment
+// with the host's env= entries on top. os.getenv still sees only the
+// process environment.
+func luaEnvTable(L *lua.LState, env []string) *lua.LTable {
+	table := L.NewTable()
+	for _, entry := range append(os.Environ(), env...) {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			table.RawSetString(key, lua.LString(value))
+		}
+	}
+	return table
+}
+
+// evalLua runs the script and reads the result and error_message globals
+// it sets
+func evalLua(L *lua.LState, scriptPath string) (bool, error) {
+	// Execute the Lua script
+	if err := L.DoFile(scriptPath); err != nil {
+		return false, fmt.Errorf("lua script error: %w", err)
+	}
+
+	// Get the result from the global variable 'result' set by the script
+	result := L.GetGlobal("result")
+	if result == lua.LNil {
+		return false, fmt.Errorf("lua script did not set 'result' variable")
+	}
+
+	// Convert result to boolean
+	resultBool := lua.LVAsBool(result)
+
+	// Check if there's an error message from the script
+	errorMsg := L.GetGlobal("error_message")
+	if !resultBool && errorMsg != lua.LNil {
+		return false, fmt.Errorf("lua script failed: %s", errorMsg.String())
+	}
+
+	return resultBool, nil
+}
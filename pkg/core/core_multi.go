@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MULT policies deciding how many URLs must pass
+const (
+	multiAny    = "any"
+	multiAll    = "all"
+	multiQuorum = "quorum"
+)
+
+// multiRequired parses a MULT policy (any, all, quorum, or a count) into the
+// number of URLs that must pass out of total
+func multiRequired(policy string, total int) (int, error) {
+	switch strings.ToLower(policy) {
+	case multiAny:
+		return 1, nil
+	case multiAll:
+		return total, nil
+	case multiQuorum:
+		return total/2 + 1, nil
+	}
+	n, err := strconv.Atoi(policy)
+	if err != nil || n < 1 || n > total {
+		return 0, fmt.Errorf("invalid MULT policy %q: want any, all, quorum, or a count from 1 to %d", policy, total)
+	}
+	return n, nil
+}
+
+// multiMembers parses MULT member URLs, each optionally followed by
+// weight=N (default 1)
+func multiMembers(fields []string) (urls []string, weights []int, err error) {
+	for _, field := range fields {
+		v, isWeight := strings.CutPrefix(strings.ToLower(field), "weight=")
+		if !isWeight {
+			urls = append(urls, field)
+			weights = append(weights, 1)
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, nil, fmt.Errorf("invalid MULT weight %q: want a whole number of at least 0", field)
+		}
+		if len(urls) == 0 {
+			return nil, nil, fmt.Errorf("MULT weight %q must follow the URL it weights", field)
+		}
+		weights[len(weights)-1] = n
+	}
+	return urls, weights, nil
+}
+
+// MultiHttpCheck probes several URLs concurrently and applies the policy
+// given before them: "any http://a https://b http://c:8080". With a
+// quorum=N token there's no policy field; instead the weights of the
+// passing URLs (weight=N after a URL, default 1) must add up to N. Each
+// URL's outcome is reported in the "urls" detail.
+func MultiHttpCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	fields := strings.Fields(host.HostName)
+	weighted := host.Tokens.Has("quorum")
+	var policy string
+	if !weighted {
+		if len(fields) < 2 {
+			return false, fmt.Errorf("MULT needs a policy and at least one URL (e.g. \"any http://a https://b\")")
+		}
+		policy, fields = fields[0], fields[1:]
+	}
+	urls, weights, err := multiMembers(fields)
+	if err != nil {
+		return false, err
+	}
+	if len(urls) == 0 {
+		return false, fmt.Errorf("MULT needs at least one URL")
+	}
+	total := len(urls)
+	if weighted {
+		total = 0
+		for _, w := range weights {
+			total += w
+		}
+	}
+	var required int
+	if weighted {
+		required, err = strconv.Atoi(host.Tokens.Get("quorum"))
+		if err != nil || required < 1 || required > total {
+			return false, fmt.Errorf("invalid quorum %q: want a weighted score from 1 to %d", host.Tokens.Get("quorum"), total)
+		}
+	} else if required, err = multiRequired(policy, len(urls)); err != nil {
+		return false, err
+	}
+
+	timeout, err := opts.timeoutFor(host, defaultHTTPTimeout)
+	if err != nil {
+		return false, err
+	}
+	tlsConf, err := tlsConfigFor(host, opts)
+	if err != nil {
+		return false, err
+	}
+	client, err := newCheckClient(host, tlsConf, timeout, opts)
+	if err != nil {
+		return false, err
+	}
+	defer client.CloseIdleConnections()
+
+	method := checkMethod(host)
+
+	errs := make([]error, len(urls))
+	done := make(chan struct{}, len(urls))
+	for i, url := range urls {
+		go func() {
+			errs[i] = comboProbe(ctx, host, client, method, url)
+			done <- struct{}{}
+		}()
+	}
+	for range urls {
+		<-done
+	}
+
+	passed, score := 0, 0
+	outcomes := make(map[string]string, len(urls))
+	var failures []string
+	for i, url := range urls {
+		if errs[i] == nil {
+			passed++
+			score += weights[i]
+			outcomes[url] = "ok"
+			continue
+		}
+		outcomes[url] = errs[i].Error()
+		failures = append(failures, fmt.Sprintf("%s: %v", url, errs[i]))
+	}
+	SetDetail(ctx, "urls", outcomes)
+	SetDetail(ctx, "urlsPassed", fmt.Sprintf("%d/%d", passed, len(urls)))
+
+	if weighted {
+		SetDetail(ctx, "weightedScore", fmt.Sprintf("%d/%d", score, total))
+		SetDetail(ctx, "quorum", required)
+		if score < required {
+			return false, fmt.Errorf("weighted score %d/%d, quorum needs %d - %s", score, total, required, strings.Join(failures, "; "))
+		}
+		return true, nil
+	}
+	if passed < required {
+		return false, fmt.Errorf("%d/%d URLs passed, %s needs %d - %s", passed, len(urls), strings.ToLower(policy), required, strings.Join(failures, "; "))
+	}
+	return true, nil
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// SNTP packet layout (RFC 4330): a 48-byte header with 64-bit timestamps
+// counting seconds (high 32 bits) and fractions (low 32 bits) since 1900
+const (
+	ntpPacketSize = 48
+	ntpEpochDelta = 2208988800 // seconds from 1900 to the Unix epoch
+
+	ntpVersion    = 4
+	ntpModeClient = 3
+	ntpModeServer = 4
+
+	ntpLeapUnsynced = 3 // leap indicator "clock not synchronized"
+)
+
+// ntpTimestamp encodes t as an NTP timestamp
+func ntpTimestamp(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpochDelta)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+// ntpTime decodes an NTP timestamp
+func ntpTime(ts uint64) time.Time {
+	secs := int64(ts>>32) - ntpEpochDelta
+	nanos := (ts & 0xffffffff) * uint64(time.Second) >> 32
+	return time.Unix(secs, int64(nanos))
+}
+
+// NTPCheck sends an SNTP client request to host[:port] (123 by default) and
+// passes when the server answers with a valid, synchronized timestamp. The
+// clock offset and stratum are reported as details; maxoffset= fails the
+// check when the server's clock is further than that from ours.
+func NTPCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	// SNTP is UDP, which the SOCKS5 dialer doesn't carry
+	if opts.proxied() {
+		return false, fmt.Errorf("NTP: %w", ErrProxyUnsupported)
+	}
+
+	var maxOffset time.Duration
+	if v := host.Tokens.Get("maxoffset"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return false, fmt.Errorf("invalid maxoffset %q", v)
+		}
+		maxOffset = d
+	}
+
+	timeout, err := opts.timeoutFor(host, defaultNTPTimeout)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := opts.dialContext(ctx, "udp", hostAddr(host.HostName, "NTP"))
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// The transmit timestamp doubles as a nonce: servers echo it back as the
+	// originate timestamp, which ties the reply to this request
+	req := make([]byte, ntpPacketSize)
+	req[0] = ntpVersion<<3 | ntpModeClient
+	sent := time.Now()
+	binary.BigEndian.PutUint64(req[40:], ntpTimestamp(sent))
+	if _, err := conn.Write(req); err != nil {
+		return false, err
+	}
+
+	resp := make([]byte, ntpPacketSize)
+	n, err := conn.Read(resp)
+	received := time.Now()
+	if err != nil {
+		return false, err
+	}
+	if n < ntpPacketSize {
+		return false, fmt.Errorf("short NTP response (%d bytes)", n)
+	}
+
+	leap, mode, stratum := resp[0]>>6, resp[0]&0x7, resp[1]
+	if mode != ntpModeServer {
+		return false, fmt.Errorf("unexpected NTP mode %d in response", mode)
+	}
+	if binary.BigEndian.Uint64(resp[24:]) != binary.BigEndian.Uint64(req[40:]) {
+		return false, fmt.Errorf("NTP response doesn't match the request")
+	}
+	if stratum == 0 {
+		// Kiss-o'-Death: the reference ID carries an ASCII code (RATE, DENY, ...)
+		return false, fmt.Errorf("NTP server refused the request (kiss code %q)", resp[12:16])
+	}
+	transmit := binary.BigEndian.Uint64(resp[40:])
+	if transmit == 0 {
+		return false, fmt.Errorf("NTP response has no transmit timestamp")
+	}
+
+	// offset = ((t2 - t1) + (t3 - t4)) / 2 and delay = (t4 - t1) - (t3 - t2)
+	serverReceive := ntpTime(binary.BigEndian.Uint64(resp[32:]))
+	serverTransmit := ntpTime(transmit)
+	offset := (serverReceive.Sub(sent) + serverTransmit.Sub(received)) / 2
+	delay := received.Sub(sent) - serverTransmit.Sub(serverReceive)
+
+	SetDetail(ctx, "stratum", int(stratum))
+	SetDetail(ctx, "offsetMs", float64(offset.Microseconds())/1000)
+	SetDetail(ctx, "delayMs", float64(delay.Microseconds())/1000)
+
+	if leap == ntpLeapUnsynced {
+		return false, fmt.Errorf("NTP server clock is not synchronized (stratum %d)", stratum)
+	}
+	if maxOffset > 0 && offset.Abs() > maxOffset {
+		return false, fmt.Errorf("clock offset %s exceeds maxoffset %s", offset.Round(time.Microsecond), maxOffset)
+	}
+	return true, nil
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// CheckFunc is the signature every check type implements. It returns
+// (true, nil) when the check passes and (false, err) with details when it
+// fails. ctx bounds the whole check; opts carries run-wide settings.
+type CheckFunc func(ctx context.Context, host Host, opts *Options) (bool, error)
+
+// Built-in timeouts used when neither --timeout nor timeout= is set
+const (
+	defaultHTTPTimeout = 5 * time.Second
+	defaultPingTimeout = 2 * time.Second
+	defaultNTPTimeout  = 2 * time.Second
+	defaultTCPTimeout  = 5 * time.Second
+	defaultSVCTimeout  = 10 * time.Second
+)
+
+// defaultTimeouts maps check types to their built-in timeout; types not
+// listed (scripts) run without a deadline
+var defaultTimeouts = map[string]time.Duration{
+	"ICMP": defaultPingTimeout,
+	"HTTP": defaultHTTPTimeout,
+	"HTPS": defaultHTTPTimeout,
+	"COMB": defaultHTTPTimeout,
+	"MULT": defaultHTTPTimeout,
+	"DOH":  defaultHTTPTimeout,
+	"DOT":  defaultHTTPTimeout,
+	"CERT": defaultHTTPTimeout,
+	"NTP":  defaultNTPTimeout,
+	"TCP":  defaultTCPTimeout,
+	"SVC":  defaultSVCTimeout,
+	"JRPC": defaultHTTPTimeout,
+}
+
+// Options carries run-wide settings shared by all check functions
+type Options struct {
+	// Timeout is the default per-check timeout used when the host line
+	// doesn't set timeout=. Zero keeps each check type's built-in default.
+	Timeout time.Duration
+
+	// RootCAs verifies HTTPS servers when the host doesn't set cacert=;
+	// nil means the system roots
+	RootCAs *x509.CertPool
+
+	// CAAppend makes per-host cacert= bundles extend the system roots
+	// instead of replacing them
+	CAAppend bool
+
+	// Dialer opens TCP connections for network checks; nil dials directly.
+	// Set to a SOCKS5 dialer to route checks through a bastion.
+	Dialer proxy.ContextDialer
+
+	// Resolver looks up host names for network checks that don't go
+	// through Dialer; nil means net.DefaultResolver
+	Resolver Resolver
+
+	// KeepAlive is the TCP keep-alive period for check connections; zero
+	// keeps Go's default and negative turns keep-alives off
+	KeepAlive time.Duration
+
+	// IdleTimeout is how long an HTTP check's idle connection is kept for
+	// reuse within the check (redirects, COMB and MULT requests to the
+	// same server); zero keeps the transport default of 90 seconds
+	IdleTimeout time.Duration
+
+	// MaxBodySize caps how much of a response body HTTP-family checks read
+	// (--max-body-size); zero keeps the 10MB default. maxbody= overrides it.
+	MaxBodySize int64
+
+	// CombSchemes remembers which scheme answered each COMB host so later
+	// runs probe only that one (--comb-sticky); nil probes both every time
+	CombSchemes *SchemeCache
+
+	// Processes limits concurrent external processes started by ICMP and
+	// script checks; nil is unlimited
+	Processes ProcessPool
+
+	// PingBin, PythonBin, and PwshBin override the binaries ICMP, PY, and
+	// PS checks run; empty means look them up on PATH
+	PingBin   string
+	PythonBin string
+	PwshBin   string
+
+	// ICMPMode picks how ICMP checks ping (--icmp-mode): auto, dgram, raw,
+	// or exec; empty means auto
+	ICMPMode string
+
+	// SSHBin overrides the ssh client via= checks run; empty means ssh
+	// on PATH
+	SSHBin string
+
+	// BaselineDir holds the stored response bodies diff= checks compare
+	// against; UpdateBaseline replaces them with the current responses
+	BaselineDir    string
+	UpdateBaseline bool
+
+	// Data is the --data-file document handed to LUA, PY, and PS checks;
+	// nil means none
+	Data *ScriptData
+}
+
+// proxied reports whether checks must egress through a proxy dialer
+func (o *Options) proxied() bool {
+	return o != nil && o.Dialer != nil
+}
+
+// DefaultOptions returns the options used when the caller sets none
+func DefaultOptions() *Options {
+	return &Options{}
+}
+
+// timeoutFor resolves the effective timeout for a host: the timeout= token,
+// then the run-wide default, then the check type's fallback (0 = none)
+func (o *Options) timeoutFor(host Host, fallback time.Duration) (time.Duration, error) {
+	if v := host.Tokens.Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid timeout %q", v)
+		}
+		return d, nil
+	}
+	if o != nil && o.Timeout > 0 {
+		return o.Timeout, nil
+	}
+	return fallback, nil
+}
+
+// EffectiveTimeout reports the timeout a check of host would run with
+// (0 = no deadline)
+func (o *Options) EffectiveTimeout(host Host) (time.Duration, error) {
+	return o.timeoutFor(host, defaultTimeouts[host.CheckType])
+}
+
+// withTimeout derives a context bounded by d, or an uncancelled child when
+// d is zero
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return context.WithCancel(ctx)
+}
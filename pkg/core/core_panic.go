@@ -0,0 +1,35 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError reports a check function that panicked. Stack is the
+// goroutine's stack at the point of the panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("check panicked: %v", e.Value)
+}
+
+// CallCheck runs a check function, turning a panic into a *PanicError so one
+// broken check can't take down the whole run. Panics in goroutines the check
+// starts itself aren't caught. The host's ipv= family is put in ctx for the
+// dialers.
+func CallCheck(ctx context.Context, checkFunc CheckFunc, host Host, opts *Options) (passed bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			passed, err = false, &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	ctx, err = withIPFamily(ctx, host, opts)
+	if err != nil {
+		return false, err
+	}
+	return checkFunc(ctx, host, opts)
+}
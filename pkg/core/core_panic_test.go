@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCallCheckRecoversPanic(t *testing.T) {
+	panicking := func(ctx context.Context, host Host, opts *Options) (bool, error) {
+		panic("boom")
+	}
+	ran := false
+	normal := func(ctx context.Context, host Host, opts *Options) (bool, error) {
+		ran = true
+		return true, nil
+	}
+
+	var results []Result
+	for _, check := range []CheckFunc{panicking, normal} {
+		host := Host{CheckType: "TEST", HostName: "example.test"}
+		started := time.Now()
+		passed, err := CallCheck(context.Background(), check, host, nil)
+		results = append(results, NewResult(host, passed, err, started, time.Since(started)))
+	}
+
+	if !ran {
+		t.Fatal("check after the panicking one didn't run")
+	}
+	if results[0].Status != StatusErrored {
+		t.Errorf("panicking check status = %q, want %q", results[0].Status, StatusErrored)
+	}
+	var panicErr *PanicError
+	if !errors.As(results[0].Err, &panicErr) {
+		t.Fatalf("panicking check error = %v, want a *PanicError", results[0].Err)
+	}
+	if panicErr.Value != "boom" {
+		t.Errorf("PanicError.Value = %v, want boom", panicErr.Value)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Error("PanicError.Stack is empty")
+	}
+	if results[1].Status != StatusPassed {
+		t.Errorf("normal check status = %q, want %q", results[1].Status, StatusPassed)
+	}
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxPingCount bounds count= so a typo can't start an hour-long ping
+const maxPingCount = 100
+
+// Ping summary lines. Unix (iputils, BSD/macOS, busybox):
+//
+//	10 packets transmitted, 9 received, 10% packet loss, time 9013ms
+//	rtt min/avg/max/mdev = 0.031/0.045/0.062/0.009 ms
+//
+// Windows:
+//
+//	Packets: Sent = 10, Received = 9, Lost = 1 (10% loss),
+//	Minimum = 1ms, Maximum = 3ms, Average = 2ms
+var (
+	reUnixPingCounts = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	reUnixPingRTT    = regexp.MustCompile(`= [\d.]+/([\d.]+)/`)
+	reWinPingCounts  = regexp.MustCompile(`Sent = (\d+), Received = (\d+)`)
+	reWinPingRTT     = regexp.MustCompile(`Average = (\d+)ms`)
+)
+
+// pingStats is the parsed summary of a multi-packet ping run
+type pingStats struct {
+	sent, received int
+	avgRTT         float64 // milliseconds; 0 when no reply arrived
+}
+
+// lossPercent is the share of packets that got no reply
+func (s pingStats) lossPercent() float64 {
+	if s.sent == 0 {
+		return 100
+	}
+	return float64(s.sent-s.received) * 100 / float64(s.sent)
+}
+
+// parsePingSummary extracts packet counts and the average round trip from
+// ping's output, accepting both the Unix and Windows formats
+func parsePingSummary(output string) (pingStats, bool) {
+	var stats pingStats
+	counts := reUnixPingCounts.FindStringSubmatch(output)
+	rtt := reUnixPingRTT.FindStringSubmatch(output)
+	if counts == nil {
+		counts = reWinPingCounts.FindStringSubmatch(output)
+		rtt = reWinPingRTT.FindStringSubmatch(output)
+	}
+	if counts == nil {
+		return stats, false
+	}
+	stats.sent, _ = strconv.Atoi(counts[1])
+	stats.received, _ = strconv.Atoi(counts[2])
+	if rtt != nil {
+		stats.avgRTT, _ = strconv.ParseFloat(rtt[1], 64)
+	}
+	return stats, true
+}
+
+// pingCount resolves the count= token (default 1)
+func pingCount(host Host) (int, error) {
+	v := host.Tokens.Get("count")
+	if v == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxPingCount {
+		return 0, fmt.Errorf("invalid count %q: must be 1-%d", v, maxPingCount)
+	}
+	return n, nil
+}
+
+// pingMaxLoss resolves the maxloss= token, a percentage with or without
+// the % sign. Without one, a run passes as long as any reply arrives.
+func pingMaxLoss(host Host) (float64, bool, error) {
+	v := host.Tokens.Get("maxloss")
+	if v == "" {
+		return 0, false, nil
+	}
+	loss, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	if err != nil || loss < 0 || loss > 100 {
+		return 0, false, fmt.Errorf("invalid maxloss %q: must be a percentage 0-100", v)
+	}
+	return loss, true, nil
+}
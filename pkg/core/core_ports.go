@@ -0,0 +1,40 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultPorts maps check types that dial a fixed service to the port used
+// when the host doesn't give one. COMB probes the HTTP and HTPS ports.
+var DefaultPorts = map[string]int{
+	"HTTP": 80,
+	"HTPS": 443,
+	"DOT":  853,
+	"CERT": 443,
+	"NTP":  123,
+}
+
+// SetDefaultPort overrides the default port of a check type
+func SetDefaultPort(checkType string, port int) error {
+	code := CanonicalCheckType(checkType)
+	if _, ok := DefaultPorts[code]; !ok {
+		return fmt.Errorf("check type %s has no default port", code)
+	}
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("port %d out of range (1-65535)", port)
+	}
+	DefaultPorts[code] = port
+	return nil
+}
+
+// hostAddr returns hostName as host:port, adding the check type's default
+// port unless the host already names one
+func hostAddr(hostName, checkType string) string {
+	if _, _, err := net.SplitHostPort(hostName); err == nil {
+		return hostName
+	}
+	return net.JoinHostPort(strings.Trim(hostName, "[]"), strconv.Itoa(DefaultPorts[checkType]))
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// ProcessPool caps how many external processes (ping, python, pwsh) checks
+// run at once, independent of how many network checks are in flight. A nil
+// pool is unlimited.
+type ProcessPool chan struct{}
+
+// NewProcessPool returns a pool of n process slots, or nil (unlimited)
+// when n isn't positive
+func NewProcessPool(n int) ProcessPool {
+	if n <= 0 {
+		return nil
+	}
+	return make(ProcessPool, n)
+}
+
+// acquireProcess waits for a process slot, giving up when ctx ends. The
+// returned function releases the slot.
+func (o *Options) acquireProcess(ctx context.Context) (func(), error) {
+	if o == nil || o.Processes == nil {
+		return func() {}, nil
+	}
+	select {
+	case o.Processes <- struct{}{}:
+		return func() { <-o.Processes }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a process slot: %w", ctx.Err())
+	}
+}
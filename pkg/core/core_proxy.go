@@ -0,0 +1,37 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/proxy"
+)
+
+// ErrProxyUnsupported is returned by check types that can't be routed
+// through the configured SOCKS5 proxy
+var ErrProxyUnsupported = errors.New("check type can't be proxied through SOCKS5")
+
+// NewSOCKS5Dialer builds a dialer for a SOCKS5 proxy given as
+// [user:pass@]host:port
+func NewSOCKS5Dialer(spec string) (proxy.ContextDialer, error) {
+	u, err := url.Parse("socks5://" + strings.TrimPrefix(spec, "socks5://"))
+	if err != nil || u.Host == "" || u.Port() == "" {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy %q: want [user:pass@]host:port", spec)
+	}
+
+	var auth *proxy.Auth
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = &proxy.Auth{User: u.User.Username(), Password: password}
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", u.Host, auth, &net.Dialer{})
+	if err != nil {
+		return nil, fmt.Errorf("SOCKS5 proxy %s: %w", u.Host, err)
+	}
+	// The x/net SOCKS5 dialer always implements ContextDialer
+	return dialer.(proxy.ContextDialer), nil
+}
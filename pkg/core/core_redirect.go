@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// redirectDrainCap bounds how much of a redirect's body is read so its
+// connection can be reused
+const redirectDrainCap = 64 << 10
+
+// maxHops resolves the host's maxhops= token; ok is false when unset
+func maxHops(host Host) (hops int, ok bool, err error) {
+	v := host.Tokens.Get("maxhops")
+	if v == "" {
+		return 0, false, nil
+	}
+	hops, err = strconv.Atoi(v)
+	if err != nil || hops < 0 {
+		return 0, false, fmt.Errorf("invalid maxhops %q: want a count of 0 or more", v)
+	}
+	return hops, true, nil
+}
+
+// doCheckRequest sends an HTTP or HTPS check's request. With maxhops= it
+// follows redirects itself (followRedirects); otherwise the client's
+// default policy applies.
+func doCheckRequest(ctx context.Context, client *http.Client, host Host, req *http.Request) (*http.Response, error) {
+	hops, ok, err := maxHops(host)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return client.Do(req)
+	}
+	return followRedirects(ctx, client, host, req, hops)
+}
+
+// followRedirects follows redirects one hop at a time, failing when the
+// chain takes more than maxHops hops or comes back to a URL it already
+// visited. The error and the redirectChain detail list every URL, so a loop
+// reads as one rather than as the client's generic "stopped after 10
+// redirects". The final non-redirect response is returned.
+func followRedirects(ctx context.Context, client *http.Client, host Host, req *http.Request, maxHops int) (*http.Response, error) {
+	manual := *client
+	manual.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	chain := []string{req.URL.String()}
+	visited := map[string]bool{req.URL.String(): true}
+	defer func() {
+		SetDetail(ctx, "redirectChain", chain)
+		SetDetail(ctx, "redirectHops", len(chain)-1)
+	}()
+	for {
+		resp, err := manual.Do(req)
+		if err != nil {
+			if len(chain) > 1 {
+				return nil, fmt.Errorf("redirect chain %s: %w", strings.Join(chain, " -> "), err)
+			}
+			return nil, err
+		}
+		next, err := resp.Location()
+		if !isRedirect(resp.StatusCode) || errors.Is(err, http.ErrNoLocation) {
+			return resp, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, redirectDrainCap))
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("redirect %d from %s: invalid Location: %w", resp.StatusCode, chain[len(chain)-1], err)
+		}
+
+		url := next.String()
+		chain = append(chain, url)
+		if visited[url] {
+			return nil, fmt.Errorf("redirect loop: %s", strings.Join(chain, " -> "))
+		}
+		if len(chain)-1 > maxHops {
+			return nil, fmt.Errorf("redirect chain exceeds maxhops %d: %s", maxHops, strings.Join(chain, " -> "))
+		}
+		visited[url] = true
+
+		if req, err = redirectRequest(ctx, host, req.Method, resp.StatusCode, url); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isRedirect reports whether a status code is a redirect the client would
+// follow
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// redirectRequest builds the request for the next hop the way net/http
+// does: 307 and 308 repeat the method and body= payload, while 301, 302,
+// and 303 switch to a bodyless GET (HEAD stays HEAD)
+func redirectRequest(ctx context.Context, host Host, method string, code int, url string) (*http.Request, error) {
+	if code == http.StatusTemporaryRedirect || code == http.StatusPermanentRedirect {
+		return newCheckRequest(ctx, host, method, url)
+	}
+	if method != http.MethodHead {
+		method = http.MethodGet
+	}
+	return http.NewRequestWithContext(ctx, method, url, nil)
+}
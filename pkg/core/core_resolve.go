@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// minAddrDialTimeout is the least time each resolved address gets when a
+// check's deadline is shared between them, as net.Dialer does
+const minAddrDialTimeout = 2 * time.Second
+
+// Resolver looks up the addresses of a host name. *net.Resolver implements
+// it, so net.DefaultResolver or a resolver with a custom Dial can be used
+// as is; tests and split-horizon setups can supply their own.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// resolver returns the resolver checks use: opts.Resolver, or
+// net.DefaultResolver when none is set
+func (o *Options) resolver() Resolver {
+	if o != nil && o.Resolver != nil {
+		return o.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// dialContext opens a connection for a check. TCP goes through the proxy
+// dialer when one is configured (the proxy resolves the name). Otherwise
+// the host name is resolved with opts.Resolver and each address is tried
+// in turn with a share of the deadline; with no resolver set, net.Dialer
+// resolves and dials as usual (racing IPv6 and IPv4).
+// An ipv= family in ctx pins the network, and so the addresses, to it.
+func (o *Options) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	family := familyFrom(ctx)
+	network = familyNetwork(network, family)
+	if o.proxied() && (network == "tcp" || network == "tcp4" || network == "tcp6") {
+		return o.Dialer.DialContext(ctx, network, addr)
+	}
+	dialer := &net.Dialer{}
+	if o != nil {
+		dialer.KeepAlive = o.KeepAlive
+	}
+	if o == nil || o.Resolver == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	ips, err := o.Resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	if ips = familyAddrs(ips, family); len(ips) == 0 {
+		return nil, noFamilyAddress(host, family)
+	}
+	var errs []error
+	for i, ip := range ips {
+		// A blackholed first address mustn't use up the whole deadline
+		addrCtx, cancel := addrDeadline(ctx, len(ips)-i)
+		conn, err := dialer.DialContext(addrCtx, network, net.JoinHostPort(ip.String(), port))
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// addrDeadline gives one of the remaining addresses its share of ctx's
+// deadline, but at least minAddrDialTimeout (or whatever is left)
+func addrDeadline(ctx context.Context, remaining int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	left := time.Until(deadline)
+	share := left / time.Duration(remaining)
+	if share < minAddrDialTimeout {
+		share = min(left, minAddrDialTimeout)
+	}
+	return context.WithTimeout(ctx, share)
+}
+
+// resolveHost returns the first address opts.Resolver gives for name, for
+// checks that hand the target to an external program (ping). Names pass
+// through untouched when no resolver is set, so the program resolves them,
+// unless ctx carries an ipv= family: then the name is always resolved
+// here so the program gets an address in that family.
+func (o *Options) resolveHost(ctx context.Context, name string) (string, error) {
+	family := familyFrom(ctx)
+	if ip := net.ParseIP(name); ip != nil {
+		if !inFamily(ip, family) {
+			return "", fmt.Errorf("%s is not an IPv%s address", name, family)
+		}
+		return name, nil
+	}
+	if family == "" && (o == nil || o.Resolver == nil) {
+		return name, nil
+	}
+	ips, err := o.resolver().LookupIPAddr(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("lookup %s: no addresses", name)
+	}
+	if ips = familyAddrs(ips, family); len(ips) == 0 {
+		return "", noFamilyAddress(name, family)
+	}
+	return ips[0].String(), nil
+}
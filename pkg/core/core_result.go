@@ -0,0 +1,49 @@
+package core
+
+import "time"
+
+// Status is the outcome category of a check result
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusErrored Status = "error"
+	StatusUnknown Status = "unknown"
+	StatusSkipped Status = "skipped"
+)
+
+// Result is the outcome of running one host's check
+type Result struct {
+	Host       Host
+	Status     Status
+	Err        error
+	SkipReason string
+	Started    time.Time
+	Duration   time.Duration
+	Details    map[string]any
+
+	// Maintenance marks a check that ran inside a maintenance window;
+	// its failures are reported as warnings and don't alert
+	Maintenance bool
+	// LowSeverity marks a check whose severity= is below --min-severity;
+	// its failures are logged but don't alert or fail the run
+	LowSeverity bool
+}
+
+// NewResult builds a result from a check function's return values
+func NewResult(host Host, passed bool, err error, started time.Time, duration time.Duration) Result {
+	status := StatusPassed
+	switch {
+	case err != nil:
+		status = StatusErrored
+	case !passed:
+		status = StatusFailed
+	}
+	return Result{Host: host, Status: status, Err: err, Started: started, Duration: duration}
+}
+
+// Passed reports whether the check passed
+func (r Result) Passed() bool {
+	return r.Status == StatusPassed
+}
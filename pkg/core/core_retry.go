@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// StatusError reports an HTTP response whose status code failed the check.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.Code)
+}
+
+// Retry classes accepted by ParseRetryOn
+const (
+	RetryOnTimeout     = "timeout"
+	RetryOn5xx         = "5xx"
+	RetryOnConnRefused = "connrefused"
+	RetryOnAll         = "all"
+)
+
+// RetryOn is the set of error classes that are considered transient.
+type RetryOn map[string]bool
+
+// ParseRetryOn parses a comma-separated list of retry classes
+// (e.g. "timeout,5xx").
+func ParseRetryOn(spec string) (RetryOn, error) {
+	classes := RetryOn{}
+	for _, class := range strings.Split(spec, ",") {
+		class = strings.ToLower(strings.TrimSpace(class))
+		switch class {
+		case "":
+			continue
+		case RetryOnTimeout, RetryOn5xx, RetryOnConnRefused, RetryOnAll:
+			classes[class] = true
+		default:
+			return nil, fmt.Errorf("unknown retry class %q (valid: timeout, 5xx, connrefused, all)", class)
+		}
+	}
+	return classes, nil
+}
+
+// ShouldRetry reports whether a failed check result falls into one of the
+// configured transient classes. Failures without an error (a check that
+// simply returned false) are only retried under "all".
+func (r RetryOn) ShouldRetry(err error) bool {
+	if r[RetryOnAll] {
+		return true
+	}
+	if err == nil {
+		return false
+	}
+
+	if r[RetryOnTimeout] {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return true
+		}
+	}
+
+	if r[RetryOnConnRefused] && errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	if r[RetryOn5xx] {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.Code >= 500 && statusErr.Code <= 599 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// maxSchemaErrors bounds how many validation errors a failure lists
+const maxSchemaErrors = 5
+
+// schemaCache holds compiled schemas by absolute path, so hosts sharing a
+// schema= file compile it once per run
+var schemaCache = struct {
+	sync.Mutex
+	schemas map[string]*jsonschema.Schema
+}{schemas: map[string]*jsonschema.Schema{}}
+
+// loadSchema compiles the JSON Schema file at path, or returns the cached
+// copy. Compile errors aren't cached, so a fixed file is picked up.
+func loadSchema(path string) (*jsonschema.Schema, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	schemaCache.Lock()
+	defer schemaCache.Unlock()
+	if sch, ok := schemaCache.schemas[abs]; ok {
+		return sch, nil
+	}
+	sch, err := jsonschema.NewCompiler().Compile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", path, err)
+	}
+	schemaCache.schemas[abs] = sch
+	return sch, nil
+}
+
+// bufferBody reads the whole response body for an assertion named by
+// purpose, and replays it into resp.Body so later body assertions still see
+// all of it. The check client caps bodies at the max body size; a longer
+// body is an error, never a truncated prefix.
+func bufferBody(host Host, resp *http.Response, purpose string) ([]byte, error) {
+	idle, err := bodyIdleTimeout(host)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(newDeadlineReader(resp.Body, idle))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		var bodyErr *BodyTimeoutError
+		if errors.As(err, &bodyErr) {
+			return nil, bodyErr
+		}
+		var tooLarge *BodyTooLargeError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("%w, too large for %s", tooLarge, purpose)
+		}
+		return nil, fmt.Errorf("read body after %d bytes: %w", len(body), err)
+	}
+	return body, nil
+}
+
+// checkSchema enforces the schema= token: the body must be JSON that
+// validates against the schema file
+func checkSchema(host Host, resp *http.Response) error {
+	path := host.Tokens.Get("schema")
+	if path == "" {
+		return nil
+	}
+	sch, err := loadSchema(path)
+	if err != nil {
+		return err
+	}
+	body, err := bufferBody(host, resp, "schema validation")
+	if err != nil {
+		return err
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("body isn't valid JSON: %w", err)
+	}
+	if err := sch.Validate(doc); err != nil {
+		var verr *jsonschema.ValidationError
+		if !errors.As(err, &verr) {
+			return err
+		}
+		return fmt.Errorf("body doesn't match schema %s: %s", path, schemaErrors(verr))
+	}
+	return nil
+}
+
+// schemaErrors lists the leaf validation errors as "at /path: message",
+// capped at maxSchemaErrors
+func schemaErrors(verr *jsonschema.ValidationError) string {
+	var msgs []string
+	for _, unit := range verr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		loc := unit.InstanceLocation
+		if loc == "" {
+			loc = "/"
+		}
+		msgs = append(msgs, fmt.Sprintf("at %s: %s", loc, unit.Error))
+	}
+	if len(msgs) == 0 {
+		return verr.Error()
+	}
+	if len(msgs) > maxSchemaErrors {
+		msgs = append(msgs[:maxSchemaErrors], fmt.Sprintf("and %d more", len(msgs)-maxSchemaErrors))
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scriptPassCodes parses the passcode= token (e.g. "0,3") into the set of
+// exit codes treated as success; the default is just 0
+func scriptPassCodes(host Host) (map[int]bool, error) {
+	spec := host.Tokens.Get("passcode")
+	if spec == "" {
+		return map[int]bool{0: true}, nil
+	}
+	codes := map[int]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid passcode %q", spec)
+		}
+		codes[code] = true
+	}
+	return codes, nil
+}
+
+// Levels an exit code can map to with map=
+const (
+	scriptLevelPass = "pass"
+	scriptLevelWarn = "warn"
+	scriptLevelFail = "fail"
+)
+
+// scriptExitMap parses the map= token (e.g. "0:pass,1:warn,2:fail") into
+// exit code levels; nil when the token is unset. It replaces passcode=, so
+// the two can't be combined.
+func scriptExitMap(host Host) (map[int]string, error) {
+	spec := host.Tokens.Get("map")
+	if spec == "" {
+		return nil, nil
+	}
+	if host.Tokens.Has("passcode") {
+		return nil, fmt.Errorf("map= and passcode= can't be combined")
+	}
+	levels := map[int]string{}
+	for _, part := range strings.Split(spec, ",") {
+		codeStr, level, ok := strings.Cut(strings.TrimSpace(part), ":")
+		code, err := strconv.Atoi(codeStr)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid map %q: expected CODE:LEVEL,...", spec)
+		}
+		switch level {
+		case scriptLevelPass, scriptLevelWarn, scriptLevelFail:
+			levels[code] = level
+		default:
+			return nil, fmt.Errorf("invalid map %q: level %q must be pass, warn, or fail", spec, level)
+		}
+	}
+	return levels, nil
+}
+
+// reEnvName matches the variable names env= tokens may set
+var reEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// scriptEnv parses the repeatable env=KEY=VALUE token into KEY=VALUE
+// entries, in config order. Values were already ${VAR}-expanded with the
+// rest of the host's tokens.
+func scriptEnv(host Host) ([]string, error) {
+	var env []string
+	for _, spec := range host.Tokens.Values("env") {
+		key, _, ok := strings.Cut(spec, "=")
+		if !ok || !reEnvName.MatchString(key) {
+			return nil, fmt.Errorf("invalid env %q: expected env=KEY=VALUE", spec)
+		}
+		env = append(env, spec)
+	}
+	return env, nil
+}
+
+// runScriptCommand runs a script process with the --data-file variables and
+// the host's env= variables, and judges its exit code against the host's pass codes. The exit code is recorded as the "exitCode" detail
+// for both passing and failing runs. With exitMap (map=) the code's level
+// decides instead: warn passes with the "exitLevel" detail set, which is
+// logged as a warning, and unmapped codes fail.
+func runScriptCommand(ctx context.Context, cmd *exec.Cmd, host Host, opts *Options, lang string, timeout time.Duration, exitMap map[int]string) (bool, error) {
+	passCodes, err := scriptPassCodes(host)
+	if err != nil {
+		return false, err
+	}
+	env, err := scriptEnv(host)
+	if err != nil {
+		return false, err
+	}
+	if opts != nil {
+		env = append(opts.Data.env(), env...)
+	}
+	if len(env) > 0 {
+		// exec keeps the last value of a duplicated key, so env= tokens
+		// override inherited variables (and the data file's)
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	output, err := cmd.CombinedOutput()
+	exitCode := 0
+	if err != nil {
+		// Script was killed at the deadline
+		if ctx.Err() == context.DeadlineExceeded {
+			return false, fmt.Errorf("%s script timed out after %s: %w", lang, timeout, ctx.Err())
+		}
+		// Anything other than a non-zero exit means the script never ran
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return false, fmt.Errorf("%s script failed: %w", lang, err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	SetDetail(ctx, "exitCode", exitCode)
+
+	if exitMap != nil {
+		level, ok := exitMap[exitCode]
+		if !ok {
+			level = scriptLevelFail
+		}
+		SetDetail(ctx, "exitLevel", level)
+		if level != scriptLevelFail {
+			return true, nil
+		}
+	} else if passCodes[exitCode] {
+		return true, nil
+	}
+
+	// Script failed - include output in error message
+	if len(output) > 0 {
+		return false, fmt.Errorf("%s script failed (exit code %d): %s", lang, exitCode, strings.TrimSpace(string(output)))
+	}
+	return false, fmt.Errorf("%s script failed: exit code %d", lang, exitCode)
+}
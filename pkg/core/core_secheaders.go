@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultHSTSMaxAge is the minimum HSTS max-age (180 days, in seconds)
+// unless hstsmaxage= sets one
+const defaultHSTSMaxAge = 180 * 24 * 60 * 60
+
+// secHeaderCheck inspects a response's headers and describes what's missing
+// or weak, or returns "" when the header is fine
+type secHeaderCheck func(h http.Header, minAge int64) string
+
+// secHeaderChecks are the names secheaders= accepts
+var secHeaderChecks = map[string]secHeaderCheck{
+	"hsts":         checkHSTS,
+	"nosniff":      checkNoSniff,
+	"frameoptions": checkFrameOptions,
+	"csp":          checkCSP,
+	"referrer":     checkReferrerPolicy,
+}
+
+// checkSecurityHeaders enforces the secheaders= token on HTPS checks: each
+// named header must be present and strong enough. Every header's outcome is
+// recorded in the "secHeaders" detail ("ok" or the problem), and the check
+// fails naming each missing or weak one.
+func checkSecurityHeaders(ctx context.Context, host Host, resp *http.Response) error {
+	spec := host.Tokens.Get("secheaders")
+	if spec == "" {
+		return nil
+	}
+	minAge := int64(defaultHSTSMaxAge)
+	if v := host.Tokens.Get("hstsmaxage"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid hstsmaxage %q: want seconds", v)
+		}
+		minAge = n
+	}
+
+	outcomes := map[string]string{}
+	var problems []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		check, ok := secHeaderChecks[name]
+		if !ok {
+			return fmt.Errorf("invalid secheaders name %q (valid: %s)", name, strings.Join(secHeaderNames(), ", "))
+		}
+		problem := check(resp.Header, minAge)
+		if problem == "" {
+			outcomes[name] = "ok"
+			continue
+		}
+		outcomes[name] = problem
+		problems = append(problems, problem)
+	}
+	SetDetail(ctx, "secHeaders", outcomes)
+	if len(problems) > 0 {
+		return fmt.Errorf("security headers: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// secHeaderNames lists the accepted secheaders= names, sorted
+func secHeaderNames() []string {
+	names := make([]string, 0, len(secHeaderChecks))
+	for name := range secHeaderChecks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// checkHSTS requires Strict-Transport-Security with a max-age of at least
+// minAge seconds
+func checkHSTS(h http.Header, minAge int64) string {
+	value := h.Get("Strict-Transport-Security")
+	if value == "" {
+		return "missing Strict-Transport-Security"
+	}
+	for _, directive := range strings.Split(value, ";") {
+		key, v, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if !strings.EqualFold(strings.TrimSpace(key), "max-age") {
+			continue
+		}
+		age, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(v), `"`), 10, 64)
+		if err != nil {
+			return fmt.Sprintf("Strict-Transport-Security has invalid max-age %q", v)
+		}
+		if age < minAge {
+			return fmt.Sprintf("Strict-Transport-Security max-age %d below %d", age, minAge)
+		}
+		return ""
+	}
+	return "Strict-Transport-Security has no max-age"
+}
+
+// checkNoSniff requires X-Content-Type-Options: nosniff
+func checkNoSniff(h http.Header, _ int64) string {
+	value := strings.TrimSpace(h.Get("X-Content-Type-Options"))
+	switch {
+	case value == "":
+		return "missing X-Content-Type-Options"
+	case !strings.EqualFold(value, "nosniff"):
+		return fmt.Sprintf("X-Content-Type-Options is %q, want nosniff", value)
+	}
+	return ""
+}
+
+// checkFrameOptions requires X-Frame-Options DENY or SAMEORIGIN; a
+// Content-Security-Policy frame-ancestors directive counts instead
+func checkFrameOptions(h http.Header, _ int64) string {
+	for _, policy := range h.Values("Content-Security-Policy") {
+		for _, directive := range strings.Split(policy, ";") {
+			if fields := strings.Fields(directive); len(fields) > 0 && strings.EqualFold(fields[0], "frame-ancestors") {
+				return ""
+			}
+		}
+	}
+	value := strings.TrimSpace(h.Get("X-Frame-Options"))
+	switch {
+	case value == "":
+		return "missing X-Frame-Options"
+	case !strings.EqualFold(value, "DENY") && !strings.EqualFold(value, "SAMEORIGIN"):
+		return fmt.Sprintf("X-Frame-Options is %q, want DENY or SAMEORIGIN", value)
+	}
+	return ""
+}
+
+// checkCSP requires a non-empty Content-Security-Policy
+func checkCSP(h http.Header, _ int64) string {
+	if strings.TrimSpace(h.Get("Content-Security-Policy")) == "" {
+		return "missing Content-Security-Policy"
+	}
+	return ""
+}
+
+// checkReferrerPolicy requires a non-empty Referrer-Policy
+func checkReferrerPolicy(h http.Header, _ int64) string {
+	if strings.TrimSpace(h.Get("Referrer-Policy")) == "" {
+		return "missing Referrer-Policy"
+	}
+	return ""
+}
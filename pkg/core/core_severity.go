@@ -0,0 +1,47 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity ranks how much a host's failure matters. Higher is more severe.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityInfo:     "info",
+	SeverityWarning:  "warning",
+	SeverityCritical: "critical",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses critical, warning, or info (any case)
+func ParseSeverity(v string) (Severity, error) {
+	for s, name := range severityNames {
+		if strings.EqualFold(v, name) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid severity %q: want critical, warning, or info", v)
+}
+
+// Severity returns the host's severity= level. Hosts without one are
+// critical, so every failure counts unless the config says otherwise.
+func (h Host) Severity() (Severity, error) {
+	if !h.Tokens.Has("severity") {
+		return SeverityCritical, nil
+	}
+	return ParseSeverity(h.Tokens.Get("severity"))
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MaxTime returns the host's maxtime= latency threshold, or 0 when unset.
+// A check that passes but takes longer than this is reported as failed.
+func (h Host) MaxTime() (time.Duration, error) {
+	v := h.Tokens.Get("maxtime")
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid maxtime %q", v)
+	}
+	return d, nil
+}
+
+// Budget returns how many failed or slow checks the host's budget= token
+// allows over a run, or -1 when no budget is set
+func (h Host) Budget() (int, error) {
+	v := h.Tokens.Get("budget")
+	if v == "" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return -1, fmt.Errorf("invalid budget %q", v)
+	}
+	return n, nil
+}
+
+// EnforceMaxTime turns a passed result into a failure when it took longer
+// than the host's maxtime=. TCP checks apply maxtime= to the connect
+// themselves, so a slow banner read isn't counted.
+func EnforceMaxTime(r Result) Result {
+	if r.Status != StatusPassed || r.Host.CheckType == "TCP" {
+		return r
+	}
+	limit, err := r.Host.MaxTime()
+	if err != nil {
+		r.Status = StatusErrored
+		r.Err = err
+		return r
+	}
+	if limit > 0 && r.Duration > limit {
+		r.Status = StatusFailed
+		r.Err = fmt.Errorf("took %s, over maxtime %s", r.Duration.Round(time.Microsecond), limit)
+	}
+	return r
+}
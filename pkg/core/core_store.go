@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ResultStore persists or forwards a finished run's results (a history
+// database, a notifier, a time-series backend, ...). Save gets every result
+// of the run, skipped checks included, and filters what it doesn't need.
+type ResultStore interface {
+	Save(ctx context.Context, results []Result) error
+}
+
+// NopStore is a ResultStore that discards results
+type NopStore struct{}
+
+// Save implements ResultStore
+func (NopStore) Save(context.Context, []Result) error { return nil }
+
+// NamedResultStore pairs a registered store with its name for logging
+type NamedResultStore struct {
+	Name  string
+	Store ResultStore
+}
+
+var (
+	storesMu     sync.Mutex
+	resultStores []NamedResultStore
+)
+
+// RegisterResultStore adds a store that receives every run's results.
+// Registering an existing name replaces that store in place.
+func RegisterResultStore(name string, store ResultStore) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	for i := range resultStores {
+		if resultStores[i].Name == name {
+			resultStores[i].Store = store
+			return
+		}
+	}
+	resultStores = append(resultStores, NamedResultStore{Name: name, Store: store})
+}
+
+// ResultStores returns the registered stores in registration order
+func ResultStores() []NamedResultStore {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	return append([]NamedResultStore(nil), resultStores...)
+}
+
+// runStartedKey is the context key for the run's start time
+type runStartedKey struct{}
+
+// WithRunStarted returns a context carrying the run's start time, which
+// stores use to group results from one invocation
+func WithRunStarted(ctx context.Context, started time.Time) context.Context {
+	return context.WithValue(ctx, runStartedKey{}, started)
+}
+
+// RunStarted returns the run start time set by WithRunStarted, or the zero
+// time when there is none
+func RunStarted(ctx context.Context) time.Time {
+	started, _ := ctx.Value(runStartedKey{}).(time.Time)
+	return started
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Service manager output. sc query prints the state as
+//
+//	STATE              : 4  RUNNING
+//
+// and launchctl list <label> prints a plist-style dictionary with the
+// running process's "PID" = 123; and the "LastExitStatus" = 0; of the
+// previous one.
+var (
+	reScState         = regexp.MustCompile(`STATE\s+:\s+\d+\s+(\w+)`)
+	reLaunchctlPID    = regexp.MustCompile(`"PID"\s*=\s*(\d+);`)
+	reLaunchctlStatus = regexp.MustCompile(`"LastExitStatus"\s*=\s*(-?\d+);`)
+)
+
+// ServiceCheck passes when the local service named by the host field is
+// running: systemctl is-active on Linux, sc query on Windows, and
+// launchctl list on macOS. The service manager's raw status is reported
+// as the serviceStatus detail. It checks the machine netcheck runs on, so
+// the host field is a unit name (nginx.service), not a network address.
+func ServiceCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	unit := host.HostName
+	if unit == "" || strings.HasPrefix(unit, "-") {
+		return false, fmt.Errorf("invalid service name %q", unit)
+	}
+
+	timeout, err := opts.timeoutFor(host, defaultSVCTimeout)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	var parse func(string) (string, bool, bool)
+	switch runtime.GOOS {
+	case "windows":
+		cmd, parse = exec.CommandContext(ctx, "sc", "query", unit), parseScQuery
+	case "darwin":
+		cmd, parse = exec.CommandContext(ctx, "launchctl", "list", unit), parseLaunchctlList
+	default:
+		cmd, parse = exec.CommandContext(ctx, "systemctl", "is-active", unit), parseSystemctlIsActive
+	}
+
+	release, err := opts.acquireProcess(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	// All three exit non-zero for a stopped or unknown service, so the
+	// output decides; the exit status only matters when it can't be read
+	out, runErr := cmd.CombinedOutput()
+	var execErr *exec.Error
+	if errors.As(runErr, &execErr) || ctx.Err() != nil {
+		return false, fmt.Errorf("query service %s: %w", unit, runErr)
+	}
+	status, running, ok := parse(string(out))
+	if !ok {
+		if runErr != nil {
+			return false, fmt.Errorf("query service %s: %w: %s", unit, runErr, strings.TrimSpace(string(out)))
+		}
+		return false, fmt.Errorf("query service %s: unrecognised output %q", unit, strings.TrimSpace(string(out)))
+	}
+	SetDetail(ctx, "serviceStatus", status)
+	if !running {
+		return false, fmt.Errorf("service %s is %s", unit, status)
+	}
+	return true, nil
+}
+
+// parseSystemctlIsActive reads systemctl is-active's one-word state;
+// only "active" counts as running
+func parseSystemctlIsActive(out string) (string, bool, bool) {
+	status, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	status = strings.TrimSpace(status)
+	if status == "" || strings.ContainsAny(status, " \t") {
+		return "", false, false
+	}
+	return status, status == "active", true
+}
+
+// parseScQuery reads the STATE line of sc query; only RUNNING counts. A
+// service that doesn't exist reports OpenService FAILED 1060 instead.
+func parseScQuery(out string) (string, bool, bool) {
+	if m := reScState.FindStringSubmatch(out); m != nil {
+		return m[1], m[1] == "RUNNING", true
+	}
+	if strings.Contains(out, "1060") {
+		return "NOT_FOUND", false, true
+	}
+	return "", false, false
+}
+
+// parseLaunchctlList reads launchctl list <label>: a PID means running,
+// otherwise the last exit status is reported. An unknown label prints
+// "Could not find service".
+func parseLaunchctlList(out string) (string, bool, bool) {
+	if m := reLaunchctlPID.FindStringSubmatch(out); m != nil {
+		return "running (pid " + m[1] + ")", true, true
+	}
+	if m := reLaunchctlStatus.FindStringSubmatch(out); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return fmt.Sprintf("not running (last exit %d)", code), false, true
+	}
+	if strings.Contains(out, "Could not find service") {
+		return "not loaded", false, true
+	}
+	return "", false, false
+}
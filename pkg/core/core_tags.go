@@ -0,0 +1,20 @@
+package core
+
+import (
+	"slices"
+	"strings"
+)
+
+// Tags returns the host's tag= values in config order. A tag= value may
+// list several tags separated by commas; blanks and repeats are dropped.
+func (h Host) Tags() []string {
+	var tags []string
+	for _, value := range h.Tokens.Values("tag") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// TcpCheck opens a TCP connection to host:port and passes when the
+// handshake completes. The dial time is reported as the connectMs detail;
+// with maxtime= the check fails when the handshake alone took longer, even
+// though the port is open. version= reads the server's banner line. There's
+// no default port, so the host must name one.
+func TcpCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	if host.Tokens.Has("via") {
+		return viaCheck(ctx, host, opts)
+	}
+	if _, _, err := net.SplitHostPort(host.HostName); err != nil {
+		return false, fmt.Errorf("TCP needs host:port, got %q", host.HostName)
+	}
+	maxTime, err := host.MaxTime()
+	if err != nil {
+		return false, err
+	}
+
+	timeout, err := opts.timeoutFor(host, defaultTCPTimeout)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := opts.dialContext(ctx, "tcp", host.HostName)
+	elapsed := time.Since(start)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	SetDetail(ctx, "connectMs", float64(elapsed.Microseconds())/1000)
+	if maxTime > 0 && elapsed > maxTime {
+		return false, fmt.Errorf("connect took %s, over maxtime %s", elapsed.Round(time.Microsecond), maxTime)
+	}
+	if err := checkBanner(ctx, host, conn); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ConnectTimeoutError reports a connection that wasn't established within
+// connect-timeout=, as opposed to a server that's slow to respond
+type ConnectTimeoutError struct {
+	Addr string
+	// Limit is the connect-timeout= that expired
+	Limit time.Duration
+}
+
+func (e *ConnectTimeoutError) Error() string {
+	return fmt.Sprintf("connect to %s timed out after %s (connect-timeout)", e.Addr, e.Limit)
+}
+
+// Timeout and Temporary make the error a net.Error so --retry-on timeout
+// covers it
+func (e *ConnectTimeoutError) Timeout() bool   { return true }
+func (e *ConnectTimeoutError) Temporary() bool { return true }
+
+// connectTimeout resolves the host's connect-timeout= token, 0 when unset
+func connectTimeout(host Host) (time.Duration, error) {
+	v := host.Tokens.Get("connect-timeout")
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid connect-timeout %q", v)
+	}
+	return d, nil
+}
+
+// newCheckClient is newHTTPClient for an HTTP-family check: timeout bounds
+// the whole request, the host's connect-timeout= bounds each dial, and
+// response bodies are capped at the max body size
+func newCheckClient(host Host, tlsConf *tls.Config, timeout time.Duration, opts *Options) (*http.Client, error) {
+	limit, err := connectTimeout(host)
+	if err != nil {
+		return nil, err
+	}
+	maxBody, err := opts.maxBodySize(host)
+	if err != nil {
+		return nil, err
+	}
+	client := newHTTPClient(tlsConf, timeout, opts)
+	if limit > 0 {
+		transport := client.Transport.(*http.Transport)
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			deadline := time.Now().Add(limit)
+			if parent, ok := ctx.Deadline(); ok && parent.Before(deadline) {
+				// the overall timeout expires first, so it's the one that applies
+				return dial(ctx, network, addr)
+			}
+			dialCtx, cancel := context.WithDeadline(ctx, deadline)
+			defer cancel()
+			conn, err := dial(dialCtx, network, addr)
+			// the dialer may report its own deadline as an i/o timeout
+			// before dialCtx is marked done
+			var netErr net.Error
+			if err != nil && ctx.Err() == nil && (dialCtx.Err() != nil || errors.As(err, &netErr) && netErr.Timeout()) {
+				return nil, &ConnectTimeoutError{Addr: addr, Limit: limit}
+			}
+			return conn, err
+		}
+	}
+	client.Transport = &bodyCapTransport{base: client.Transport, limit: maxBody}
+	return client, nil
+}
+
+// describeTimeout says which phase a timed-out request was in, so "can't
+// reach the server" reads differently from "server is slow to respond".
+// The phase is recorded as the timeoutPhase detail; other errors pass
+// through.
+func describeTimeout(ctx context.Context, err error, trace *phaseTrace, timeout time.Duration) error {
+	var connectErr *ConnectTimeoutError
+	if errors.As(err, &connectErr) {
+		SetDetail(ctx, "timeoutPhase", "connect")
+		return err
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return err
+	}
+	phase := trace.timedOutPhase()
+	SetDetail(ctx, "timeoutPhase", phase)
+	return fmt.Errorf("timed out after %s during %s: %w", timeout, phase, err)
+}
+
+// timedOutPhase names the phase a timed-out request was in: the last one
+// that started without finishing, else waiting for the first response byte
+func (t *phaseTrace) timedOutPhase() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(tracePhases) - 1; i >= 0; i-- {
+		phase := tracePhases[i]
+		if _, started := t.started[phase]; !started {
+			continue
+		}
+		if _, done := t.durations[phase]; !done {
+			return phase
+		}
+	}
+	return "ttfb"
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// LoadCertPool reads a PEM CA bundle into a certificate pool. When
+// appendSystem is set the bundle is added to the system roots rather than
+// replacing them.
+func LoadCertPool(path string, appendSystem bool) (*x509.CertPool, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ca certificate %s: %w", path, err)
+	}
+
+	pool := x509.NewCertPool()
+	if appendSystem {
+		if pool, err = x509.SystemCertPool(); err != nil {
+			return nil, fmt.Errorf("load system certificate pool: %w", err)
+		}
+	}
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// tlsConfigFor builds the TLS client configuration for a host from its
+// clientcert/clientkey/cacert tokens, falling back to the run-wide CA bundle.
+// Paths may use ${VAR} references, which are resolved by Host.Expanded
+// before the check runs.
+func tlsConfigFor(host Host, opts *Options) (*tls.Config, error) {
+	conf := &tls.Config{}
+	if opts != nil {
+		conf.RootCAs = opts.RootCAs
+	}
+
+	// Client certificate for mutual TLS - cert and key must come as a pair
+	certPath := host.Tokens.Get("clientcert")
+	keyPath := host.Tokens.Get("clientkey")
+	if certPath != "" || keyPath != "" {
+		if certPath == "" || keyPath == "" {
+			return nil, fmt.Errorf("clientcert and clientkey must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate %s / key %s: %w", certPath, keyPath, err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	// Per-host CA pool replaces the system roots (or extends them with --ca-append)
+	if caPath := host.Tokens.Get("cacert"); caPath != "" {
+		pool, err := LoadCertPool(caPath, opts != nil && opts.CAAppend)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+
+	return conf, nil
+}
+
+// newHTTPClient returns an HTTP client with the given check timeout that
+// uses the given TLS configuration for HTTPS requests. Connections go
+// through opts.Dialer when a proxy is configured, resolve names with
+// opts.Resolver when one is set, and stick to the ipv= family in the
+// request's context. Every check gets its own transport, so no
+// connection outlives the check that opened it; checks close the client's
+// idle connections when they finish.
+func newHTTPClient(tlsConf *tls.Config, timeout time.Duration, opts *Options) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConf
+	if opts.proxied() {
+		// The SOCKS5 dialer replaces any HTTP(S)_PROXY from the environment
+		transport.Proxy = nil
+		transport.DialContext = opts.Dialer.DialContext
+	} else if opts != nil && (opts.Resolver != nil || opts.KeepAlive != 0) {
+		transport.DialContext = opts.dialContext
+	} else {
+		// Keep the default dialer, pinned to the check's ipv= family if any
+		dial := transport.DialContext
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dial(ctx, familyNetwork(network, familyFrom(ctx)), addr)
+		}
+	}
+	if opts != nil && opts.IdleTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleTimeout
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// NewHTTPClient returns a client for netcheck's own requests (fetching
+// configs, not running checks). It verifies servers with the run's CA
+// settings and dials through the proxy when one is configured.
+func NewHTTPClient(opts *Options, timeout time.Duration) *http.Client {
+	conf := &tls.Config{}
+	if opts != nil {
+		conf.RootCAs = opts.RootCAs
+	}
+	return newHTTPClient(conf, timeout, opts)
+}
+
+// describeTLSError rewrites certificate verification failures so the message
+// says whether the chain or the hostname failed. Other errors pass through.
+func describeTLSError(err error) error {
+	var hostErr x509.HostnameError
+	if errors.As(err, &hostErr) {
+		return fmt.Errorf("tls hostname verification failed: %w", err)
+	}
+	var authErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &authErr) || errors.As(err, &invalidErr) {
+		return fmt.Errorf("tls certificate chain verification failed: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Precompiled regex for ${VAR} references in config values
+var reVarRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandVars replaces ${VAR} references with values from the environment.
+// Only the braced form is expanded so values like regexes can contain '$'.
+func ExpandVars(value string) string {
+	return reVarRef.ReplaceAllStringFunc(value, func(ref string) string {
+		return os.Getenv(reVarRef.FindStringSubmatch(ref)[1])
+	})
+}
+
+// Tokens holds the per-host key=value options parsed from a config line,
+// e.g. "clientcert=client.pem". Keys are stored lowercase and may repeat.
+type Tokens map[string][]string
+
+// Get returns the last value set for key, or "" if the key is absent.
+func (t Tokens) Get(key string) string {
+	values := t[key]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Values returns every value set for key in config order.
+func (t Tokens) Values(key string) []string {
+	return t[key]
+}
+
+// Has reports whether key was set at all.
+func (t Tokens) Has(key string) bool {
+	_, ok := t[key]
+	return ok
+}
+
+// Expanded returns a copy of the tokens with ${VAR} references resolved
+func (t Tokens) Expanded() Tokens {
+	if t == nil {
+		return nil
+	}
+	expanded := make(Tokens, len(t))
+	for key, values := range t {
+		for _, value := range values {
+			expanded.Add(key, ExpandVars(value))
+		}
+	}
+	return expanded
+}
+
+// Add appends value to the values for key.
+func (t Tokens) Add(key, value string) {
+	t[key] = append(t[key], value)
+}
+
+// ParseSize parses a byte size such as "100", "512KB", or "1MB"
+// (binary multiples, case-insensitive).
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		mult   int64
+	}{
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSuffix(s, unit.suffix)
+			multiplier = unit.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return n * multiplier, nil
+}
+
+// commonTokens apply to every check type; the runner handles them
+var commonTokens = []string{"id", "timeout", "maxtime", "budget", "depends", "maint", "priority", "severity", "tag"}
+
+// Token groups shared by several check types
+var (
+	httpTokens = []string{"method", "body", "contenttype", "minsize", "maxsize", "bodytimeout", "setcookie", "noheader", "schema", "connect-timeout", "maxbody", "ipv"}
+	tlsTokens  = []string{"cacert", "clientcert", "clientkey"}
+)
+
+// checkTypeTokens lists the tokens each built-in check type reads, on top
+// of commonTokens
+var checkTypeTokens = map[string][][]string{
+	"ICMP": {{"count", "maxloss", "via", "ipv"}},
+	"HTTP": {httpTokens, tracePhases, {"diff", "version", "versionfrom", "jsonlen", "transfer", "maxhops", "via"}},
+	"HTPS": {httpTokens, tlsTokens, tracePhases, {"diff", "version", "versionfrom", "jsonlen", "transfer", "maxhops", "via", "resume", "secheaders", "hstsmaxage"}},
+	"COMB": {httpTokens, tlsTokens, {"fast"}},
+	"MULT": {httpTokens, tlsTokens, {"quorum"}},
+	"DOH":  {tlsTokens, {"query", "qtype", "ipv"}},
+	"DOT":  {tlsTokens, {"query", "qtype", "ipv"}},
+	"CERT": {tlsTokens, {"mindays", "ocsp", "resume", "minkey", "sigalg", "chain", "ipv"}},
+	"NTP":  {{"maxoffset", "ipv"}},
+	"TCP":  {{"version", "via", "ipv"}},
+	"SVC":  {},
+	"JRPC": {tlsTokens, {"method", "params", "bodytimeout", "connect-timeout", "maxbody", "ipv"}},
+	"LUA":  {{"env"}},
+	"PY":   {{"passcode", "env", "map"}},
+	"PS":   {{"passcode", "env"}},
+}
+
+// UnknownTokens returns the sorted keys of tokens that checkType doesn't
+// read, so typos like timout=5s can be reported. Check types without a
+// token list (unknown or added at runtime) report nothing.
+func UnknownTokens(checkType string, tokens Tokens) []string {
+	groups, ok := checkTypeTokens[checkType]
+	if !ok {
+		return nil
+	}
+	var unknown []string
+	for key := range tokens {
+		if !slices.Contains(commonTokens, key) && !slices.ContainsFunc(groups, func(g []string) bool { return slices.Contains(g, key) }) {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Request phases measured by phaseTrace, in the order they happen. Each name
+// doubles as a limit token (ttfb=500ms, or ttfb<500ms on a text line).
+var tracePhases = []string{"dns", "connect", "tls_handshake", "ttfb"}
+
+// phaseTrace records when each phase of an HTTP request starts and ends
+type phaseTrace struct {
+	mu        sync.Mutex
+	start     time.Time
+	started   map[string]time.Time
+	durations map[string]time.Duration
+}
+
+// withPhaseTrace attaches an httptrace to ctx that times DNS lookup, TCP
+// connect, TLS handshake, and time to first response byte
+func withPhaseTrace(ctx context.Context) (context.Context, *phaseTrace) {
+	t := &phaseTrace{
+		start:     time.Now(),
+		started:   map[string]time.Time{},
+		durations: map[string]time.Duration{},
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { t.begin("dns") },
+		DNSDone:           func(httptrace.DNSDoneInfo) { t.end("dns") },
+		ConnectStart:      func(string, string) { t.begin("connect") },
+		ConnectDone:       func(string, string, error) { t.end("connect") },
+		TLSHandshakeStart: func() { t.begin("tls_handshake") },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.end("tls_handshake") },
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.durations["ttfb"] = time.Since(t.start)
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+func (t *phaseTrace) begin(phase string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Keep the first start when a phase repeats (e.g. dual-stack connects)
+	if _, ok := t.started[phase]; !ok {
+		t.started[phase] = time.Now()
+	}
+}
+
+func (t *phaseTrace) end(phase string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if start, ok := t.started[phase]; ok {
+		t.durations[phase] = time.Since(start)
+	}
+}
+
+// report records the measured phases in the "phases" detail (milliseconds)
+// and enforces any per-phase limit tokens the host sets
+func (t *phaseTrace) report(ctx context.Context, host Host) error {
+	t.mu.Lock()
+	durations := make(map[string]time.Duration, len(t.durations))
+	for phase, d := range t.durations {
+		durations[phase] = d
+	}
+	t.mu.Unlock()
+
+	phases := make(map[string]float64, len(durations))
+	for phase, d := range durations {
+		phases[phase] = float64(d.Microseconds()) / 1000
+	}
+	SetDetail(ctx, "phases", phases)
+
+	for _, phase := range tracePhases {
+		v := host.Tokens.Get(phase)
+		if v == "" {
+			continue
+		}
+		limit, err := time.ParseDuration(v)
+		if err != nil || limit <= 0 {
+			return fmt.Errorf("invalid %s limit %q", phase, v)
+		}
+		// Phases that didn't happen (no DNS for an IP, no TLS over http) pass
+		if d, ok := durations[phase]; ok && d > limit {
+			return fmt.Errorf("%s took %s, over limit %s", phase, d.Round(time.Microsecond), limit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// defaultVersionSource is where HTTP checks look for the version when the
+// host doesn't set versionfrom=
+const defaultVersionSource = "header:Server"
+
+// maxBannerBytes caps how much of a TCP banner is read for version=
+const maxBannerBytes = 1024
+
+// versionPattern compiles the version= token (usually written version~=),
+// or returns nil when it's unset. The pattern has to match a whole version:
+// 1.4.2 matches "nginx/1.4.2" and "v1.4.2" but not 1.4.20 or 11.4.2.
+func versionPattern(host Host) (*regexp.Regexp, error) {
+	v := host.Tokens.Get("version")
+	if v == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(`(?:^|[^0-9.])(?:` + v + `)(?:$|[^0-9.]|\.$|\.[^0-9])`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %w", v, err)
+	}
+	return re, nil
+}
+
+// matchVersion reports the detected and expected versions as details and
+// fails when the detected one doesn't match
+func matchVersion(ctx context.Context, host Host, re *regexp.Regexp, detected string) error {
+	expected := host.Tokens.Get("version")
+	SetDetail(ctx, "version", detected)
+	SetDetail(ctx, "expectedVersion", expected)
+	if !re.MatchString(detected) {
+		return fmt.Errorf("version %q doesn't match expected %s", detected, expected)
+	}
+	return nil
+}
+
+// checkVersion enforces version= on HTTP and HTPS checks. The version is
+// read from a response header or a JSON body field, as versionfrom= says
+// (header:NAME or json:PATH, where PATH is dotted keys and array indexes).
+// The body is replayed into resp.Body for evaluateResponse.
+func checkVersion(ctx context.Context, host Host, resp *http.Response) error {
+	re, err := versionPattern(host)
+	if err != nil || re == nil {
+		return err
+	}
+	if !statusAccepted(resp.StatusCode) {
+		// evaluateResponse fails it; an error page has no version to read
+		return nil
+	}
+
+	source := host.Tokens.Get("versionfrom")
+	if source == "" {
+		source = defaultVersionSource
+	}
+	kind, name, _ := strings.Cut(source, ":")
+	if name == "" {
+		return fmt.Errorf("invalid versionfrom %q: want header:NAME or json:PATH", source)
+	}
+
+	var detected string
+	switch kind {
+	case "header":
+		detected = resp.Header.Get(name)
+		if detected == "" {
+			return fmt.Errorf("no %s header to read the version from", http.CanonicalHeaderKey(name))
+		}
+	case "json":
+		body, err := bufferBody(host, resp, "versionfrom=json")
+		if err != nil {
+			return err
+		}
+		if detected, err = jsonField(body, name); err != nil {
+			return fmt.Errorf("read version: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid versionfrom %q: want header:NAME or json:PATH", source)
+	}
+	return matchVersion(ctx, host, re, detected)
+}
+
+// jsonField returns the string or number at a dotted path in a JSON body
+func jsonField(body []byte, path string) (string, error) {
+	value, err := jsonValue(body, path)
+	if err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("field %s isn't a string or number", path)
+}
+
+// checkBanner enforces version= on TCP checks: the first line the server
+// sends (an SSH, SMTP, or FTP greeting) must carry the expected version
+func checkBanner(ctx context.Context, host Host, conn net.Conn) error {
+	re, err := versionPattern(host)
+	if err != nil || re == nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+	line, err := bufio.NewReader(io.LimitReader(conn, maxBannerBytes)).ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		if err == nil || err == io.EOF {
+			return fmt.Errorf("server sent no banner to read the version from")
+		}
+		return fmt.Errorf("read banner: %w", err)
+	}
+	return matchVersion(ctx, host, re, line)
+}
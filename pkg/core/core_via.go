@@ -0,0 +1,200 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// viaConnectTimeout bounds the SSH connection to a via= host, on top of the
+// check's own timeout
+const viaConnectTimeout = 10 * time.Second
+
+// sshConnectFailed is the exit status ssh uses for its own errors, as
+// opposed to the status of the remote command
+const sshConnectFailed = 255
+
+// viaTokens are the tokens a check run through via= honours, on top of
+// commonTokens; the remote probe can't do the rest
+var viaTokens = map[string][]string{
+	"ICMP": {"count", "maxloss"},
+	"HTTP": {"method"},
+	"HTPS": {"method"},
+	"TCP":  {},
+}
+
+// ViaError reports that the via= host couldn't be reached over SSH, so the
+// check itself never ran
+type ViaError struct {
+	Via string
+	Err error
+}
+
+func (e *ViaError) Error() string {
+	return fmt.Sprintf("via %s: ssh connection failed: %v", e.Via, e.Err)
+}
+
+func (e *ViaError) Unwrap() error {
+	return e.Err
+}
+
+// viaCheck runs the host's check from the via= host instead of locally: it
+// SSHes there with the system ssh client (so ~/.ssh/config, keys, and the
+// agent apply) and runs ping, curl, or nc against the target. ICMP, HTTP,
+// HTPS, and TCP are supported; the remote probe only tests reachability
+// (and the status code for HTTP), so check-specific tokens it can't honour
+// are errors rather than silently skipped.
+func viaCheck(ctx context.Context, host Host, opts *Options) (bool, error) {
+	via := host.Tokens.Get("via")
+	if via == "" || strings.HasPrefix(via, "-") {
+		return false, fmt.Errorf("invalid via %q: want [user@]host", via)
+	}
+	allowed, ok := viaTokens[host.CheckType]
+	if !ok {
+		return false, fmt.Errorf("via= isn't supported for %s checks", host.CheckType)
+	}
+	for key := range host.Tokens {
+		if key != "via" && !slices.Contains(commonTokens, key) && !slices.Contains(allowed, key) {
+			return false, fmt.Errorf("%s isn't supported with via=", key)
+		}
+	}
+	if opts.proxied() {
+		return false, fmt.Errorf("via=: %w", ErrProxyUnsupported)
+	}
+
+	timeout, err := opts.timeoutFor(host, defaultTimeouts[host.CheckType])
+	if err != nil {
+		return false, err
+	}
+	seconds := strconv.Itoa(pingWaitSeconds(timeout))
+
+	var probe []string
+	var count int
+	switch host.CheckType {
+	case "ICMP":
+		if count, err = pingCount(host); err != nil {
+			return false, err
+		}
+		probe = []string{"ping", "-c", strconv.Itoa(count), "-W", seconds, host.HostName}
+		// Packets go out a second apart
+		timeout += time.Duration(count) * time.Second
+	case "HTTP", "HTPS":
+		probe = curlProbe(host, seconds)
+	case "TCP":
+		addrHost, port, err := net.SplitHostPort(host.HostName)
+		if err != nil {
+			return false, fmt.Errorf("TCP needs host:port, got %q", host.HostName)
+		}
+		probe = []string{"nc", "-z", "-w", seconds, addrHost, port}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout+viaConnectTimeout)
+	defer cancel()
+
+	sshBin := "ssh"
+	if opts != nil && opts.SSHBin != "" {
+		sshBin = opts.SSHBin
+	}
+	cmd := exec.CommandContext(ctx, sshBin,
+		"-o", "BatchMode=yes",
+		"-o", "ConnectTimeout="+strconv.Itoa(int(viaConnectTimeout/time.Second)),
+		"--", via, shellJoin(probe))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	release, err := opts.acquireProcess(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	SetDetail(ctx, "via", via)
+	runErr := cmd.Run()
+	exitCode := 0
+	if runErr != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return false, fmt.Errorf("via %s: timed out after %s: %w", via, timeout+viaConnectTimeout, ctx.Err())
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return false, &ViaError{Via: via, Err: runErr}
+		}
+		exitCode = exitErr.ExitCode()
+		if exitCode == sshConnectFailed {
+			return false, &ViaError{Via: via, Err: errors.New(remoteOutput(stderr.Bytes(), "exit code 255"))}
+		}
+	}
+	SetDetail(ctx, "remoteExitCode", exitCode)
+
+	switch host.CheckType {
+	case "ICMP":
+		maxLoss, hasMaxLoss, err := pingMaxLoss(host)
+		if err != nil {
+			return false, err
+		}
+		if host.Tokens.Has("count") || hasMaxLoss {
+			passed, err := judgePingOutput(ctx, stdout.String(), runErr, hasMaxLoss, maxLoss)
+			if err != nil {
+				return false, fmt.Errorf("via %s: %w", via, err)
+			}
+			return passed, nil
+		}
+	case "HTTP", "HTPS":
+		if exitCode == 0 {
+			code, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+			if err != nil {
+				return false, fmt.Errorf("via %s: unrecognised curl output %q", via, strings.TrimSpace(stdout.String()))
+			}
+			SetDetail(ctx, "statusCode", code)
+			if !statusAccepted(code) {
+				return false, fmt.Errorf("via %s: %w", via, &StatusError{Code: code})
+			}
+			return true, nil
+		}
+	}
+	if exitCode != 0 {
+		return false, fmt.Errorf("via %s: %s failed (exit code %d): %s", via, probe[0], exitCode, remoteOutput(stderr.Bytes(), "no output"))
+	}
+	return true, nil
+}
+
+// remoteOutput trims a remote command's stderr for an error message
+func remoteOutput(output []byte, fallback string) string {
+	if s := strings.TrimSpace(string(output)); s != "" {
+		return s
+	}
+	return fallback
+}
+
+// shellJoin quotes args for the remote shell ssh hands the command to
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// curlProbe is the remote command for an HTTP or HTPS via= check. HEAD
+// needs -I: with -X HEAD curl waits for the body the headers announce.
+func curlProbe(host Host, seconds string) []string {
+	scheme := "http://"
+	if host.CheckType == "HTPS" {
+		scheme = "https://"
+	}
+	probe := []string{"curl", "-sSL", "-o", "/dev/null", "-w", "%{http_code}", "--max-time", seconds}
+	if method := checkMethod(host); method == http.MethodHead {
+		probe = append(probe, "-I")
+	} else {
+		probe = append(probe, "-X", method)
+	}
+	return append(probe, scheme+hostAddr(host.HostName, host.CheckType))
+}
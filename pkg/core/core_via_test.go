@@ -0,0 +1,26 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCurlProbeMethod(t *testing.T) {
+	base := []string{"curl", "-sSL", "-o", "/dev/null", "-w", "%{http_code}", "--max-time", "5"}
+	tests := []struct {
+		method string
+		want   []string
+	}{
+		{"HEAD", []string{"-I"}},
+		{"head", []string{"-I"}},
+		{"GET", []string{"-X", "GET"}},
+		{"POST", []string{"-X", "POST"}},
+	}
+	for _, tt := range tests {
+		host := Host{CheckType: "HTTP", HostName: "example.com", Tokens: Tokens{"method": {tt.method}}}
+		want := slices.Concat(base, tt.want, []string{"http://example.com:80"})
+		if got := curlProbe(host, "5"); !slices.Equal(got, want) {
+			t.Errorf("method=%s: probe = %q, want %q", tt.method, got, want)
+		}
+	}
+}